@@ -0,0 +1,180 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clusterSlots Redis集群的slot数量
+const clusterSlots = 16384
+
+// HashTag 生成带hash tag的key，tag相同的key在集群模式下位于同一个slot，可以在一个命令、脚本或事务中操作。
+// 如 HashTag("user:1", "profile") 返回 {user:1}:profile
+func HashTag(tag string, parts ...string) string {
+	return "{" + tag + "}:" + strings.Join(parts, ":")
+}
+
+// KeySlot 返回key在集群模式下的slot，key中有hash tag时只计算tag部分
+func KeySlot(key string) int {
+	return int(crc16(hashTagKey(key)) % clusterSlots)
+}
+
+// hashTagKey 返回key中参与分片计算的部分，有hash tag时为tag，否则为整个key
+func hashTagKey(key string) string {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			return key[start+1 : start+1+end]
+		}
+	}
+	return key
+}
+
+// crc16 CRC16-CCITT(XMODEM)，与Redis集群计算slot的算法一致
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crossSlot 多key命令只会发送到第一个key所在的节点，key可能位于不同节点时不能使用。
+// 集群模式按slot判断；Ring按hash tag分片，tag不同的key可能位于不同分片
+func (r *RedisClient) crossSlot(keys []string) bool {
+	if len(keys) < 2 {
+		return false
+	}
+	var shard func(key string) string
+	switch r.rdb.(type) {
+	case *redis.ClusterClient:
+		shard = func(key string) string { return strconv.Itoa(KeySlot(key)) }
+	case *redis.Ring:
+		shard = hashTagKey
+	default:
+		return false
+	}
+	first := shard(keys[0])
+	for _, key := range keys[1:] {
+		if shard(key) != first {
+			return true
+		}
+	}
+	return false
+}
+
+// MGetContext 批量获取缓存，结果与keys一一对应，不存在的key对应nil。
+// 集群和Ring模式下key可能位于不同节点时自动拆分为按节点分组的pipeline
+func (r *RedisClient) MGetContext(ctx context.Context, keys ...string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	st := time.Now()
+	values := make([][]byte, len(keys))
+	var err error
+	if r.crossSlot(keys) {
+		cmds := make([]*redis.StringCmd, len(keys))
+		_, _ = r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.Get(ctx, key)
+			}
+			return nil
+		})
+		// Pipelined只返回第一个失败命令的错误，需要逐个检查，只有redis.Nil表示key不存在
+		for i, cmd := range cmds {
+			v, e := cmd.Bytes()
+			if e == nil {
+				values[i] = v
+			} else if !errors.Is(e, redis.Nil) {
+				err = e
+				break
+			}
+		}
+	} else {
+		var res []any
+		res, err = r.rdb.MGet(ctx, keys...).Result()
+		for i, v := range res {
+			if s, ok := v.(string); ok {
+				values[i] = []byte(s)
+			}
+		}
+	}
+	r.logger.Info().Strs("keys", keys).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("mget redis finish")
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// MSetContext 批量设置缓存，expiration为0表示不过期。
+// 不过期且key位于同一个节点时使用MSET，否则使用pipeline逐个设置，不保证原子性
+func (r *RedisClient) MSetContext(ctx context.Context, values map[string]any, expiration time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// 日志中的key顺序固定
+	sort.Strings(keys)
+	st := time.Now()
+	var err error
+	if expiration == 0 && !r.crossSlot(keys) {
+		err = r.rdb.MSet(ctx, values).Err()
+	} else {
+		_, err = r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, k := range keys {
+				pipe.Set(ctx, k, values[k], expiration)
+			}
+			return nil
+		})
+	}
+	r.logger.Info().Strs("keys", keys).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("mset redis finish")
+	return err
+}
+
+// Pipelined 在一次往返中执行fn中的多个命令，不保证原子性，返回各命令的结果和第一个错误
+func (r *RedisClient) Pipelined(ctx context.Context, fn func(pipe redis.Pipeliner) error) ([]redis.Cmder, error) {
+	st := time.Now()
+	cmds, err := r.rdb.Pipelined(ctx, fn)
+	r.logger.Info().Int("commands", len(cmds)).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("pipeline redis finish")
+	return cmds, err
+}
+
+// TxPipelined 使用MULTI/EXEC原子执行fn中的多个命令，集群模式下所有key需要位于同一个slot，见 HashTag
+func (r *RedisClient) TxPipelined(ctx context.Context, fn func(pipe redis.Pipeliner) error) ([]redis.Cmder, error) {
+	st := time.Now()
+	cmds, err := r.rdb.TxPipelined(ctx, fn)
+	r.logger.Info().Int("commands", len(cmds)).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("multi exec redis finish")
+	return cmds, err
+}
+
+// Transaction 乐观锁事务：WATCH keys 后调用fn，fn中通过 tx.TxPipelined 提交修改。
+// keys在提交前被其他客户端修改时重新执行fn，最多执行maxRetries+1次，仍冲突时返回 redis.TxFailedErr
+func (r *RedisClient) Transaction(ctx context.Context, fn func(tx *redis.Tx) error, maxRetries int, keys ...string) error {
+	// maxRetries小于0时至少执行一次
+	maxRetries = max(maxRetries, 0)
+	st := time.Now()
+	var err error
+	for i := 0; i <= maxRetries; i++ {
+		err = r.rdb.Watch(ctx, fn, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	r.logger.Info().Strs("keys", keys).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("transaction redis finish")
+	return err
+}
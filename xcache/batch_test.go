@@ -0,0 +1,228 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31c3), crc16("123456789"))
+	assert.Equal(t, 12182, KeySlot("foo"))
+	assert.Equal(t, KeySlot("user:1"), KeySlot(HashTag("user:1", "profile")))
+	assert.Equal(t, KeySlot(HashTag("user:1", "profile")), KeySlot(HashTag("user:1", "orders")))
+	assert.Equal(t, "{user:1}:orders:2024", HashTag("user:1", "orders", "2024"))
+	// 空的hash tag按整个key计算
+	assert.Equal(t, int(crc16("{}key")%clusterSlots), KeySlot("{}key"))
+}
+
+func TestMGetMSet(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	require.NoError(t, client.MSetContext(ctx, map[string]any{"a": "1", "b": []byte("2")}, 0))
+	require.NoError(t, client.MSetContext(ctx, map[string]any{"c": 3}, time.Minute))
+	assert.Equal(t, time.Minute, mr.TTL("c"))
+
+	values, err := client.MGetContext(ctx, "a", "missing", "b", "c")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("1"), nil, []byte("2"), []byte("3")}, values)
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, MSetJSON(ctx, client, map[string]user{"u:1": {Name: "alice"}, "u:2": {Name: "bob"}}, time.Minute))
+	users, err := MGetJSON[user](ctx, client, "u:1", "u:2", "u:3")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]user{"u:1": {Name: "alice"}, "u:2": {Name: "bob"}}, users)
+
+	_, err = MGetJSON[user](ctx, client, "a")
+	assert.Error(t, err)
+}
+
+func TestMGetCrossSlot(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	defer rdb.Close()
+	client := &RedisClient{rdb: rdb, logger: *xlog.NewLogger(xlog.LoggerConfig{Level: "error"})}
+	ctx := context.Background()
+
+	keys := []string{"missing", "a", "hash"}
+	require.True(t, client.crossSlot(keys))
+	mr.Set("a", "1")
+	values, err := client.MGetContext(ctx, "missing", "a")
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{nil, []byte("1")}, values)
+
+	// 除key不存在外的错误不能当作未命中
+	mr.HSet("hash", "field", "v")
+	_, err = client.MGetContext(ctx, keys...)
+	assert.ErrorContains(t, err, "WRONGTYPE")
+}
+
+func TestMGetMSetRing(t *testing.T) {
+	mr1, mr2 := miniredis.RunT(t), miniredis.RunT(t)
+	rdb := redis.NewRing(&redis.RingOptions{Addrs: map[string]string{"shard1": mr1.Addr(), "shard2": mr2.Addr()}})
+	defer rdb.Close()
+	client := &RedisClient{rdb: rdb, logger: *xlog.NewLogger(xlog.LoggerConfig{Level: "error"})}
+	ctx := context.Background()
+
+	keys := make([]string, 0, 20)
+	values := make(map[string]any, 20)
+	for i := 0; i < 20; i++ {
+		key := "k" + strconv.Itoa(i)
+		keys = append(keys, key)
+		values[key] = i
+	}
+	require.True(t, client.crossSlot(keys))
+	assert.False(t, client.crossSlot([]string{HashTag("user:1", "a"), HashTag("user:1", "b")}))
+
+	// key分布在不同分片时结果仍然完整
+	require.NoError(t, client.MSetContext(ctx, values, 0))
+	assert.NotEmpty(t, mr1.Keys())
+	assert.NotEmpty(t, mr2.Keys())
+	got, err := client.MGetContext(ctx, keys...)
+	require.NoError(t, err)
+	for i, v := range got {
+		assert.Equal(t, strconv.Itoa(i), string(v))
+	}
+}
+
+func TestPipelineAndTransaction(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	cmds, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, "k", "v", 0)
+		pipe.Incr(ctx, "n")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, cmds, 2)
+	assert.Equal(t, int64(1), cmds[1].(*redis.IntCmd).Val())
+
+	key := HashTag("account:1", "balance")
+	_, err = client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, 100, 0)
+		pipe.IncrBy(ctx, key, -30)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// 并发扣减余额，冲突时重试，结果不会丢失更新
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := client.Transaction(ctx, func(tx *redis.Tx) error {
+				balance, err := tx.Get(ctx, key).Int64()
+				if err != nil {
+					return err
+				}
+				if balance < 10 {
+					return errors.New("insufficient balance")
+				}
+				_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+					pipe.Set(ctx, key, balance-10, 0)
+					return nil
+				})
+				return err
+			}, 100, key)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	balance, err := client.Client().Get(ctx, key).Int64()
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), balance)
+
+	// maxRetries小于0时也会执行一次
+	calls := 0
+	require.NoError(t, client.Transaction(ctx, func(tx *redis.Tx) error {
+		calls++
+		return nil
+	}, -1, key))
+	assert.Equal(t, 1, calls)
+}
+
+func TestCollections(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	added, err := client.HSetContext(ctx, "h", "name", "alice", "age", 18)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), added)
+	v, err := client.HGetContext(ctx, "h", "name")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", string(v))
+	_, err = client.HGetContext(ctx, "h", "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	age, err := client.HIncrByContext(ctx, "h", "age", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(19), age)
+	deleted, err := client.HDelContext(ctx, "h", "age")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	all, err := client.HGetAllContext(ctx, "h")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "alice"}, all)
+
+	_, err = client.SAddContext(ctx, "s", "a", "b", "c")
+	require.NoError(t, err)
+	_, err = client.SRemContext(ctx, "s", "b")
+	require.NoError(t, err)
+	members, err := client.SMembersContext(ctx, "s")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "c"}, members)
+	ok, err := client.SIsMemberContext(ctx, "s", "b")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = client.ZAddContext(ctx, "z", redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 3, Member: "c"}, redis.Z{Score: 2, Member: "b"})
+	require.NoError(t, err)
+	score, err := client.ZIncrByContext(ctx, "z", "a", 10)
+	require.NoError(t, err)
+	assert.Equal(t, float64(11), score)
+	top, err := client.ZRangeContext(ctx, "z", 0, 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, []redis.Z{{Score: 11, Member: "a"}}, top)
+	byScore, err := client.ZRangeByScoreContext(ctx, "z", "2", "(11", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []redis.Z{{Score: 2, Member: "b"}, {Score: 3, Member: "c"}}, byScore)
+	_, err = client.ZRemContext(ctx, "z", "b")
+	require.NoError(t, err)
+	_, err = client.ZScoreContext(ctx, "z", "b")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+
+	_, err = client.RPushContext(ctx, "l", "b", "c")
+	require.NoError(t, err)
+	n, err := client.LPushContext(ctx, "l", "a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	items, err := client.LRangeContext(ctx, "l", 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, items)
+	first, err := client.LPopContext(ctx, "l")
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(first))
+	last, err := client.RPopContext(ctx, "l")
+	require.NoError(t, err)
+	assert.Equal(t, "c", string(last))
+	length, err := client.LLenContext(ctx, "l")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), length)
+	_, _ = client.LPopContext(ctx, "l")
+	_, err = client.RPopContext(ctx, "l")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
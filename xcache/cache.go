@@ -0,0 +1,60 @@
+package xcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RichXan/xcommon/xlog"
+)
+
+// NoExpiration TTLContext 对不过期的key返回的值
+const NoExpiration time.Duration = -1
+
+// Cache 缓存接口，不存在的key返回 ErrCacheMiss。
+// 业务代码依赖该接口时，可以按配置切换Redis或内存实现，单元测试使用 MemoryCache 即可
+type Cache interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+	// SetContext expiration为0表示不过期
+	SetContext(ctx context.Context, key string, value any, expiration time.Duration) error
+	ExistsContext(ctx context.Context, key string) (bool, error)
+	DeleteContext(ctx context.Context, key string) (bool, error)
+	// TTLContext 返回剩余过期时间，不过期返回 NoExpiration，不存在返回 ErrCacheMiss
+	TTLContext(ctx context.Context, key string) (time.Duration, error)
+	// ExpireContext 设置过期时间，返回key是否存在，expiration小于等于0时删除key
+	ExpireContext(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	// IncrByContext 整数自增，key不存在时从0开始，保留原有过期时间
+	IncrByContext(ctx context.Context, key string, delta int64) (int64, error)
+	// TryLock 非阻塞加锁，锁已被占用时返回 ErrLockNotAcquired
+	TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+var (
+	_ Cache = (*RedisClient)(nil)
+	_ Cache = (*TwoLevelCache)(nil)
+	_ Cache = (*MemoryCache)(nil)
+)
+
+// 缓存类型
+const (
+	CacheTypeRedis  = "redis"
+	CacheTypeMemory = "memory"
+)
+
+// CacheConfig 缓存配置，Type为空时使用Redis
+type CacheConfig struct {
+	Type  string      `yaml:"type"` // redis 或 memory，memory只适用于单实例部署
+	Redis RedisConfig `yaml:"redis"`
+}
+
+// NewCache 按配置创建缓存
+func NewCache(config *CacheConfig, logger *xlog.Logger) (Cache, error) {
+	switch config.Type {
+	case "", CacheTypeRedis:
+		return NewRedisClientByConfig(&config.Redis, logger)
+	case CacheTypeMemory:
+		return NewMemoryCache(), nil
+	default:
+		return nil, fmt.Errorf("unsupported cache type %q", config.Type)
+	}
+}
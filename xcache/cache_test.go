@@ -0,0 +1,194 @@
+package xcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// cacheBackends 返回所有实现及推进时间的方法，同一组用例验证行为一致
+func cacheBackends() map[string]func(t *testing.T) (Cache, func(time.Duration)) {
+	return map[string]func(t *testing.T) (Cache, func(time.Duration)){
+		"memory": func(t *testing.T) (Cache, func(time.Duration)) {
+			clock := &fakeClock{now: time.Now()}
+			c := NewMemoryCache()
+			c.now = clock.Now
+			return c, clock.Advance
+		},
+		"redis": func(t *testing.T) (Cache, func(time.Duration)) {
+			c, mr := newTestRedisClient(t)
+			return c, mr.FastForward
+		},
+	}
+}
+
+func TestCacheBackends(t *testing.T) {
+	for name, newCache := range cacheBackends() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("get set delete", func(t *testing.T) {
+				c, _ := newCache(t)
+				_, err := c.GetContext(ctx, "missing")
+				assert.ErrorIs(t, err, ErrCacheMiss)
+
+				require.NoError(t, c.SetContext(ctx, "k", "v", 0))
+				v, err := c.GetContext(ctx, "k")
+				require.NoError(t, err)
+				assert.Equal(t, "v", string(v))
+
+				require.NoError(t, c.SetContext(ctx, "n", 42, 0))
+				v, err = c.GetContext(ctx, "n")
+				require.NoError(t, err)
+				assert.Equal(t, "42", string(v))
+
+				exists, err := c.ExistsContext(ctx, "k")
+				require.NoError(t, err)
+				assert.True(t, exists)
+				deleted, err := c.DeleteContext(ctx, "k")
+				require.NoError(t, err)
+				assert.True(t, deleted)
+				deleted, err = c.DeleteContext(ctx, "k")
+				require.NoError(t, err)
+				assert.False(t, deleted)
+			})
+
+			t.Run("expiration", func(t *testing.T) {
+				c, advance := newCache(t)
+				require.NoError(t, c.SetContext(ctx, "k", "v", time.Minute))
+				require.NoError(t, c.SetContext(ctx, "forever", "v", 0))
+
+				ttl, err := c.TTLContext(ctx, "k")
+				require.NoError(t, err)
+				assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+				ttl, err = c.TTLContext(ctx, "forever")
+				require.NoError(t, err)
+				assert.Equal(t, NoExpiration, ttl)
+				_, err = c.TTLContext(ctx, "missing")
+				assert.ErrorIs(t, err, ErrCacheMiss)
+
+				ok, err := c.ExpireContext(ctx, "forever", 2*time.Minute)
+				require.NoError(t, err)
+				assert.True(t, ok)
+				ok, err = c.ExpireContext(ctx, "missing", time.Minute)
+				require.NoError(t, err)
+				assert.False(t, ok)
+
+				advance(time.Minute + time.Second)
+				_, err = c.GetContext(ctx, "k")
+				assert.ErrorIs(t, err, ErrCacheMiss)
+				exists, err := c.ExistsContext(ctx, "forever")
+				require.NoError(t, err)
+				assert.True(t, exists)
+
+				advance(time.Minute)
+				exists, err = c.ExistsContext(ctx, "forever")
+				require.NoError(t, err)
+				assert.False(t, exists)
+			})
+
+			t.Run("incr", func(t *testing.T) {
+				c, _ := newCache(t)
+				n, err := c.IncrByContext(ctx, "counter", 2)
+				require.NoError(t, err)
+				assert.Equal(t, int64(2), n)
+				_, err = c.ExpireContext(ctx, "counter", time.Minute)
+				require.NoError(t, err)
+				n, err = c.IncrByContext(ctx, "counter", -5)
+				require.NoError(t, err)
+				assert.Equal(t, int64(-3), n)
+				// 自增保留过期时间
+				ttl, err := c.TTLContext(ctx, "counter")
+				require.NoError(t, err)
+				assert.Greater(t, ttl, time.Duration(0))
+
+				require.NoError(t, c.SetContext(ctx, "text", "abc", 0))
+				_, err = c.IncrByContext(ctx, "text", 1)
+				assert.Error(t, err)
+			})
+
+			t.Run("lock", func(t *testing.T) {
+				c, advance := newCache(t)
+				lock, err := c.TryLock(ctx, "lock", time.Second)
+				require.NoError(t, err)
+				assert.Equal(t, "lock", lock.Key())
+
+				_, err = c.TryLock(ctx, "lock", time.Second)
+				assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+				require.NoError(t, lock.Extend(ctx, time.Minute))
+				advance(2 * time.Second)
+				_, err = c.TryLock(ctx, "lock", time.Second)
+				assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+				require.NoError(t, lock.Unlock(ctx))
+				assert.ErrorIs(t, lock.Unlock(ctx), ErrLockNotHeld)
+
+				// 过期后被其他持有者获取，原持有者不能释放
+				lock, err = c.TryLock(ctx, "lock", time.Second)
+				require.NoError(t, err)
+				advance(2 * time.Second)
+				other, err := c.TryLock(ctx, "lock", time.Second)
+				require.NoError(t, err)
+				assert.ErrorIs(t, lock.Unlock(ctx), ErrLockNotHeld)
+				assert.ErrorIs(t, lock.Extend(ctx, time.Second), ErrLockNotHeld)
+				require.NoError(t, other.Unlock(ctx))
+			})
+
+			t.Run("typed", func(t *testing.T) {
+				c, _ := newCache(t)
+				require.NoError(t, SetJSON(ctx, c, "user", map[string]int{"id": 1}, time.Minute))
+				v, err := GetJSON[map[string]int](ctx, c, "user")
+				require.NoError(t, err)
+				assert.Equal(t, 1, v["id"])
+			})
+		})
+	}
+}
+
+func TestNewCache(t *testing.T) {
+	c, err := NewCache(&CacheConfig{Type: CacheTypeMemory}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryCache{}, c)
+
+	_, err = NewCache(&CacheConfig{Type: "unknown"}, nil)
+	assert.Error(t, err)
+}
+
+func TestMemoryCacheSweep(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	c := NewMemoryCache()
+	c.now = clock.Now
+	ctx := context.Background()
+
+	for i := 0; i < memorySweepInterval-1; i++ {
+		require.NoError(t, c.SetContext(ctx, "k", i, time.Second))
+	}
+	clock.Advance(2 * time.Second)
+	require.NoError(t, c.SetContext(ctx, "other", "v", 0))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Len(t, c.items, 1)
+}
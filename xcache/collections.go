@@ -0,0 +1,195 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// HSetContext 设置哈希字段，values为 field1, value1, field2, value2 或 map[string]any，返回新增的字段数
+func (r *RedisClient) HSetContext(ctx context.Context, k string, values ...any) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.HSet(ctx, k, values...).Result()
+	r.logger.Info().Str("key", k).Int64("added", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("hset redis finish")
+	return v, e
+}
+
+// HGetContext 获取哈希字段，key或字段不存在时返回 ErrCacheMiss
+func (r *RedisClient) HGetContext(ctx context.Context, k, field string) ([]byte, error) {
+	st := time.Now()
+	v, e := r.rdb.HGet(ctx, k, field).Bytes()
+	r.logger.Info().Str("key", k).Str("field", field).Any("value", string(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("hget redis finish")
+	if errors.Is(e, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return v, e
+}
+
+// HGetAllContext 获取哈希的所有字段，key不存在时返回空map
+func (r *RedisClient) HGetAllContext(ctx context.Context, k string) (map[string]string, error) {
+	st := time.Now()
+	v, e := r.rdb.HGetAll(ctx, k).Result()
+	r.logger.Info().Str("key", k).Int("fields", len(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("hgetall redis finish")
+	return v, e
+}
+
+// HDelContext 删除哈希字段，返回删除的字段数
+func (r *RedisClient) HDelContext(ctx context.Context, k string, fields ...string) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.HDel(ctx, k, fields...).Result()
+	r.logger.Info().Str("key", k).Strs("fields", fields).Int64("deleted", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("hdel redis finish")
+	return v, e
+}
+
+// HIncrByContext 哈希字段整数自增
+func (r *RedisClient) HIncrByContext(ctx context.Context, k, field string, delta int64) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.HIncrBy(ctx, k, field, delta).Result()
+	r.logger.Info().Str("key", k).Str("field", field).Int64("value", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("hincrby redis finish")
+	return v, e
+}
+
+// SAddContext 添加集合成员，返回新增的成员数
+func (r *RedisClient) SAddContext(ctx context.Context, k string, members ...any) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.SAdd(ctx, k, members...).Result()
+	r.logger.Info().Str("key", k).Int64("added", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("sadd redis finish")
+	return v, e
+}
+
+// SRemContext 删除集合成员，返回删除的成员数
+func (r *RedisClient) SRemContext(ctx context.Context, k string, members ...any) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.SRem(ctx, k, members...).Result()
+	r.logger.Info().Str("key", k).Int64("removed", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("srem redis finish")
+	return v, e
+}
+
+// SMembersContext 获取集合的所有成员
+func (r *RedisClient) SMembersContext(ctx context.Context, k string) ([]string, error) {
+	st := time.Now()
+	v, e := r.rdb.SMembers(ctx, k).Result()
+	r.logger.Info().Str("key", k).Int("members", len(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("smembers redis finish")
+	return v, e
+}
+
+// SIsMemberContext 判断是否是集合成员
+func (r *RedisClient) SIsMemberContext(ctx context.Context, k string, member any) (bool, error) {
+	st := time.Now()
+	v, e := r.rdb.SIsMember(ctx, k, member).Result()
+	r.logger.Info().Str("key", k).Bool("is_member", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("sismember redis finish")
+	return v, e
+}
+
+// ZAddContext 添加有序集合成员，已存在的成员更新分数，返回新增的成员数
+func (r *RedisClient) ZAddContext(ctx context.Context, k string, members ...redis.Z) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.ZAdd(ctx, k, members...).Result()
+	r.logger.Info().Str("key", k).Int64("added", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("zadd redis finish")
+	return v, e
+}
+
+// ZRemContext 删除有序集合成员，返回删除的成员数
+func (r *RedisClient) ZRemContext(ctx context.Context, k string, members ...any) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.ZRem(ctx, k, members...).Result()
+	r.logger.Info().Str("key", k).Int64("removed", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("zrem redis finish")
+	return v, e
+}
+
+// ZScoreContext 获取成员的分数，成员不存在时返回 ErrCacheMiss
+func (r *RedisClient) ZScoreContext(ctx context.Context, k, member string) (float64, error) {
+	st := time.Now()
+	v, e := r.rdb.ZScore(ctx, k, member).Result()
+	r.logger.Info().Str("key", k).Str("member", member).Float64("score", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("zscore redis finish")
+	if errors.Is(e, redis.Nil) {
+		return 0, ErrCacheMiss
+	}
+	return v, e
+}
+
+// ZIncrByContext 成员分数自增，返回新的分数
+func (r *RedisClient) ZIncrByContext(ctx context.Context, k, member string, delta float64) (float64, error) {
+	st := time.Now()
+	v, e := r.rdb.ZIncrBy(ctx, k, delta, member).Result()
+	r.logger.Info().Str("key", k).Str("member", member).Float64("score", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("zincrby redis finish")
+	return v, e
+}
+
+// ZRangeContext 按排名获取成员和分数，rev为true时按分数从高到低，stop为-1表示到最后一个
+func (r *RedisClient) ZRangeContext(ctx context.Context, k string, start, stop int64, rev bool) ([]redis.Z, error) {
+	st := time.Now()
+	var v []redis.Z
+	var e error
+	if rev {
+		v, e = r.rdb.ZRevRangeWithScores(ctx, k, start, stop).Result()
+	} else {
+		v, e = r.rdb.ZRangeWithScores(ctx, k, start, stop).Result()
+	}
+	r.logger.Info().Str("key", k).Int("members", len(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("zrange redis finish")
+	return v, e
+}
+
+// ZRangeByScoreContext 获取分数在[minScore, maxScore]之间的成员，支持 -inf、+inf 和 ( 开头的开区间
+func (r *RedisClient) ZRangeByScoreContext(ctx context.Context, k, minScore, maxScore string, offset, count int64) ([]redis.Z, error) {
+	st := time.Now()
+	v, e := r.rdb.ZRangeByScoreWithScores(ctx, k, &redis.ZRangeBy{Min: minScore, Max: maxScore, Offset: offset, Count: count}).Result()
+	r.logger.Info().Str("key", k).Str("min", minScore).Str("max", maxScore).Int("members", len(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("zrangebyscore redis finish")
+	return v, e
+}
+
+// LPushContext 从列表头部插入，返回列表长度
+func (r *RedisClient) LPushContext(ctx context.Context, k string, values ...any) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.LPush(ctx, k, values...).Result()
+	r.logger.Info().Str("key", k).Int64("length", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("lpush redis finish")
+	return v, e
+}
+
+// RPushContext 从列表尾部插入，返回列表长度
+func (r *RedisClient) RPushContext(ctx context.Context, k string, values ...any) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.RPush(ctx, k, values...).Result()
+	r.logger.Info().Str("key", k).Int64("length", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("rpush redis finish")
+	return v, e
+}
+
+// LPopContext 从列表头部弹出，列表为空时返回 ErrCacheMiss
+func (r *RedisClient) LPopContext(ctx context.Context, k string) ([]byte, error) {
+	st := time.Now()
+	v, e := r.rdb.LPop(ctx, k).Bytes()
+	r.logger.Info().Str("key", k).Any("value", string(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("lpop redis finish")
+	if errors.Is(e, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return v, e
+}
+
+// RPopContext 从列表尾部弹出，列表为空时返回 ErrCacheMiss
+func (r *RedisClient) RPopContext(ctx context.Context, k string) ([]byte, error) {
+	st := time.Now()
+	v, e := r.rdb.RPop(ctx, k).Bytes()
+	r.logger.Info().Str("key", k).Any("value", string(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("rpop redis finish")
+	if errors.Is(e, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return v, e
+}
+
+// LRangeContext 获取列表[start, stop]范围内的元素，stop为-1表示到最后一个
+func (r *RedisClient) LRangeContext(ctx context.Context, k string, start, stop int64) ([]string, error) {
+	st := time.Now()
+	v, e := r.rdb.LRange(ctx, k, start, stop).Result()
+	r.logger.Info().Str("key", k).Int("values", len(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("lrange redis finish")
+	return v, e
+}
+
+// LLenContext 返回列表长度
+func (r *RedisClient) LLenContext(ctx context.Context, k string) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.LLen(ctx, k).Result()
+	r.logger.Info().Str("key", k).Int64("length", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("llen redis finish")
+	return v, e
+}
@@ -0,0 +1,60 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 窗口计数：自增后没有过期时间时设置窗口，返回计数和剩余时间
+var windowIncrScript = redis.NewScript(`
+local current = redis.call('INCRBY', KEYS[1], ARGV[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	ttl = tonumber(ARGV[2])
+end
+return {current, ttl}
+`)
+
+// Counter 窗口计数器，窗口从key第一次计数开始，到期后自动清零，适用于登录失败次数、验证码发送次数等场景
+type Counter struct {
+	client *RedisClient
+	prefix string
+	window time.Duration
+}
+
+// NewCounter 创建窗口计数器，prefix为key前缀，window为计数窗口
+func NewCounter(r *RedisClient, prefix string, window time.Duration) *Counter {
+	if window <= 0 {
+		panic("counter window must be positive")
+	}
+	return &Counter{client: r, prefix: prefix, window: window}
+}
+
+// Incr 计数加delta，返回当前窗口内的计数和窗口剩余时间
+func (c *Counter) Incr(ctx context.Context, key string, delta int64) (int64, time.Duration, error) {
+	values, err := windowIncrScript.Run(ctx, c.client.rdb, []string{c.prefix + key}, delta, c.window.Milliseconds()).Int64Slice()
+	if err != nil {
+		c.client.logger.Error().Str("key", c.prefix+key).Err(err).Msg("incr counter failed")
+		return 0, 0, err
+	}
+	return values[0], time.Duration(values[1]) * time.Millisecond, nil
+}
+
+// Get 返回当前窗口内的计数，窗口已过期时返回0
+func (c *Counter) Get(ctx context.Context, key string) (int64, error) {
+	n, err := c.client.rdb.Get(ctx, c.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// Reset 清零计数并结束当前窗口
+func (c *Counter) Reset(ctx context.Context, key string) error {
+	_, err := c.client.DeleteContext(ctx, c.prefix+key)
+	return err
+}
@@ -0,0 +1,44 @@
+package xcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounter(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+	counter := NewCounter(client, "login:fail:", time.Minute)
+
+	n, ttl, err := counter.Incr(ctx, "alice", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, time.Minute, ttl)
+
+	mr.FastForward(30 * time.Second)
+	n, ttl, err = counter.Incr(ctx, "alice", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+	// 窗口从第一次计数开始，后续计数不延长
+	assert.Equal(t, 30*time.Second, ttl)
+
+	n, err = counter.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+
+	mr.FastForward(31 * time.Second)
+	n, err = counter.Get(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, _, err = counter.Incr(ctx, "bob", 1)
+	require.NoError(t, err)
+	require.NoError(t, counter.Reset(ctx, "bob"))
+	n, err = counter.Get(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
@@ -0,0 +1,171 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// 只续期自己持有的领导权
+	electionRenewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	// 只放弃自己持有的领导权
+	electionResignScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// ElectionOptions 选主配置
+type ElectionOptions struct {
+	// 选主的key，参与同一个选举的实例必须一致，必填
+	Key string
+	// 实例标识，默认随机生成
+	ID string
+	// 领导权有效期，leader异常退出后最长经过该时间重新选主，默认15秒
+	TTL time.Duration
+	// 续期和竞选的间隔，默认TTL/3。超过 TTL-RenewInterval 未续期成功时放弃领导权
+	RenewInterval time.Duration
+	// 成为leader后在独立goroutine中调用，失去领导权时取消ctx，应尽快返回；再次竞选前会等待其返回
+	OnElected func(ctx context.Context)
+	// 失去领导权时调用
+	OnRevoked func()
+}
+
+// LeaderElection 基于Redis的选主，用于只能在一个实例上运行的后台任务
+type LeaderElection struct {
+	client *RedisClient
+	opts   ElectionOptions
+	leader atomic.Bool
+}
+
+// NewLeaderElection 创建选主，调用 Run 开始竞选
+func NewLeaderElection(r *RedisClient, opts ElectionOptions) (*LeaderElection, error) {
+	if opts.Key == "" {
+		return nil, errors.New("election key is empty")
+	}
+	if opts.ID == "" {
+		id, err := newLockToken()
+		if err != nil {
+			return nil, err
+		}
+		opts.ID = id
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 15 * time.Second
+	}
+	if opts.RenewInterval <= 0 || opts.RenewInterval >= opts.TTL {
+		opts.RenewInterval = opts.TTL / 3
+	}
+	return &LeaderElection{client: r, opts: opts}, nil
+}
+
+// ID 返回实例标识
+func (e *LeaderElection) ID() string {
+	return e.opts.ID
+}
+
+// IsLeader 当前实例是否是leader
+func (e *LeaderElection) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Leader 返回当前leader的实例标识，没有leader时返回 ErrCacheMiss
+func (e *LeaderElection) Leader(ctx context.Context) (string, error) {
+	v, err := e.client.GetContext(ctx, e.opts.Key)
+	return string(v), err
+}
+
+// Run 参与竞选直到ctx结束，退出前放弃领导权
+func (e *LeaderElection) Run(ctx context.Context) error {
+	var (
+		cancelLeader context.CancelFunc
+		wg           sync.WaitGroup
+		lastRenewed  time.Time
+	)
+	revoke := func() {
+		e.leader.Store(false)
+		cancelLeader()
+		wg.Wait()
+		e.client.logger.Info().Str("key", e.opts.Key).Str("id", e.opts.ID).Msg("leadership revoked")
+		if e.opts.OnRevoked != nil {
+			e.opts.OnRevoked()
+		}
+	}
+
+	ticker := time.NewTicker(e.opts.RenewInterval)
+	defer ticker.Stop()
+	for {
+		if e.IsLeader() {
+			// 在请求前记录时间，key的过期时间不会早于 st+TTL
+			st := time.Now()
+			// 续期请求最多等到领导权过期，网络阻塞时超时后放弃领导权
+			renewCtx, cancel := context.WithDeadline(ctx, lastRenewed.Add(e.opts.TTL))
+			n, err := electionRenewScript.Run(renewCtx, e.client.rdb, []string{e.opts.Key}, e.opts.ID, e.opts.TTL.Milliseconds()).Int64()
+			cancel()
+			switch {
+			case err == nil && n > 0:
+				lastRenewed = st
+			case err == nil:
+				// 领导权已过期或被其他实例获取
+				revoke()
+			case ctx.Err() == nil:
+				e.client.logger.Error().Str("key", e.opts.Key).Err(err).Msg("renew leadership failed")
+				// 无法确认时，下次续期前key可能已过期，提前放弃领导权，避免与新leader同时运行
+				if time.Since(lastRenewed) >= e.opts.TTL-e.opts.RenewInterval {
+					revoke()
+				}
+			}
+		}
+		if !e.IsLeader() && ctx.Err() == nil {
+			st := time.Now()
+			ok, err := e.client.rdb.SetNX(ctx, e.opts.Key, e.opts.ID, e.opts.TTL).Result()
+			if err != nil && ctx.Err() == nil {
+				e.client.logger.Error().Str("key", e.opts.Key).Err(err).Msg("campaign failed")
+			}
+			if ok {
+				lastRenewed = st
+				e.leader.Store(true)
+				e.client.logger.Info().Str("key", e.opts.Key).Str("id", e.opts.ID).Msg("elected as leader")
+				cancelLeader = e.startLeading(ctx, &wg)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				revoke()
+				resignCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+				err := electionResignScript.Run(resignCtx, e.client.rdb, []string{e.opts.Key}, e.opts.ID).Err()
+				cancel()
+				if err != nil {
+					e.client.logger.Error().Str("key", e.opts.Key).Err(err).Msg("resign leadership failed")
+				}
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// startLeading 调用 OnElected，返回失去领导权时取消其ctx的函数
+func (e *LeaderElection) startLeading(ctx context.Context, wg *sync.WaitGroup) context.CancelFunc {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	if e.opts.OnElected != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e.opts.OnElected(leaderCtx)
+		}()
+	}
+	return cancel
+}
@@ -0,0 +1,156 @@
+package xcache
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaderElection(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+
+	var elected1, revoked1, elected2 atomic.Int32
+	e1, err := NewLeaderElection(client, ElectionOptions{
+		Key:           "leader",
+		ID:            "node-1",
+		TTL:           time.Second,
+		RenewInterval: 20 * time.Millisecond,
+		OnElected: func(ctx context.Context) {
+			elected1.Add(1)
+			<-ctx.Done()
+		},
+		OnRevoked: func() { revoked1.Add(1) },
+	})
+	require.NoError(t, err)
+	e2, err := NewLeaderElection(client, ElectionOptions{
+		Key:           "leader",
+		ID:            "node-2",
+		TTL:           time.Second,
+		RenewInterval: 20 * time.Millisecond,
+		OnElected:     func(ctx context.Context) { elected2.Add(1) },
+	})
+	require.NoError(t, err)
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	done1 := make(chan struct{})
+	go func() {
+		defer close(done1)
+		_ = e1.Run(ctx1)
+	}()
+	require.Eventually(t, e1.IsLeader, time.Second, 10*time.Millisecond)
+
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	go func() { _ = e2.Run(ctx2) }()
+	time.Sleep(100 * time.Millisecond)
+	assert.False(t, e2.IsLeader())
+	leader, err := e1.Leader(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "node-1", leader)
+
+	// 领导权被其他实例抢占后回调 OnRevoked
+	mr.Set("leader", "node-3")
+	require.Eventually(t, func() bool { return revoked1.Load() == 1 }, time.Second, 10*time.Millisecond)
+	assert.False(t, e1.IsLeader())
+	require.NoError(t, client.Client().Del(context.Background(), "leader").Err())
+	require.Eventually(t, func() bool { return e1.IsLeader() || e2.IsLeader() }, time.Second, 10*time.Millisecond)
+
+	// 退出时放弃领导权，另一个实例接替
+	if e1.IsLeader() {
+		cancel1()
+		<-done1
+		assert.Equal(t, int32(2), revoked1.Load())
+		require.Eventually(t, e2.IsLeader, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int32(1), elected2.Load())
+	} else {
+		cancel1()
+		<-done1
+		assert.True(t, e2.IsLeader())
+	}
+	assert.GreaterOrEqual(t, elected1.Load(), int32(1))
+}
+
+func TestLeaderElectionRenewFailure(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+
+	revoked := make(chan struct{})
+	e, err := NewLeaderElection(client, ElectionOptions{
+		Key:           "leader",
+		TTL:           400 * time.Millisecond,
+		RenewInterval: 200 * time.Millisecond,
+		OnRevoked:     func() { close(revoked) },
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+	require.Eventually(t, e.IsLeader, time.Second, 5*time.Millisecond)
+
+	// Redis不可用时在key过期前放弃领导权
+	mr.SetError("LOADING")
+	defer mr.SetError("")
+	select {
+	case <-revoked:
+	case <-time.After(300 * time.Millisecond):
+		t.Fatal("leadership not revoked before the key expires")
+	}
+	assert.False(t, e.IsLeader())
+}
+
+// blockScriptHook 开启后阻塞脚本命令直到ctx结束，模拟网络阻塞
+type blockScriptHook struct {
+	block atomic.Bool
+}
+
+func (h *blockScriptHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *blockScriptHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.block.Load() && strings.HasPrefix(cmd.Name(), "eval") {
+			<-ctx.Done()
+			cmd.SetErr(ctx.Err())
+			return ctx.Err()
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *blockScriptHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestLeaderElectionRenewTimeout(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	hook := &blockScriptHook{}
+	client.Client().AddHook(hook)
+
+	revoked := make(chan struct{})
+	e, err := NewLeaderElection(client, ElectionOptions{
+		Key:           "leader",
+		TTL:           400 * time.Millisecond,
+		RenewInterval: 100 * time.Millisecond,
+		OnRevoked:     func() { close(revoked) },
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = e.Run(ctx) }()
+	require.Eventually(t, e.IsLeader, time.Second, 5*time.Millisecond)
+
+	// 续期请求阻塞时最晚在领导权过期时放弃
+	hook.block.Store(true)
+	select {
+	case <-revoked:
+	case <-time.After(time.Second):
+		t.Fatal("leadership not revoked after the renew deadline")
+	}
+	assert.False(t, e.IsLeader())
+}
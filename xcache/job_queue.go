@@ -0,0 +1,391 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+)
+
+// JobStatus 任务状态
+type JobStatus string
+
+const (
+	JobScheduled JobStatus = "scheduled" // 等待执行，包括等待重试
+	JobRunning   JobStatus = "running"   // 执行中
+	JobSucceeded JobStatus = "succeeded" // 执行成功
+	JobDead      JobStatus = "dead"      // 重试次数用完仍失败
+)
+
+// 任务相关的错误
+var (
+	ErrJobNotFound  = errors.New("job not found")
+	ErrDuplicateJob = errors.New("duplicate job")
+)
+
+// Job 任务
+type Job struct {
+	ID         string
+	Type       string
+	Payload    []byte
+	Status     JobStatus
+	Attempts   int // 已执行次数
+	MaxRetries int // 最大重试次数，最多执行 MaxRetries+1 次
+	RunAt      time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	FinishedAt time.Time
+	LastError  string
+	UniqueKey  string
+
+	token string // 本次执行的标识，用于确认结果时校验执行权
+}
+
+// Decode 用codec解码任务参数
+func (j *Job) Decode(codec Codec, v any) error {
+	return codec.Unmarshal(j.Payload, v)
+}
+
+// JobQueueStats 队列统计
+type JobQueueStats struct {
+	Ready   int64 // 已到执行时间、等待执行
+	Delayed int64 // 未到执行时间
+	Running int64 // 执行中
+}
+
+// 任务数据结构，所有key使用相同的hash tag，集群模式下位于同一个slot：
+// scheduled 有序集合，member为任务ID，score为执行时间；
+// running 有序集合，member为任务ID，score为可见性超时时间；
+// job:<id> 哈希，保存任务详情；unique:<key> 唯一任务对应的任务ID。
+// 任务ID在脚本中从有序集合读取，无法提前通过KEYS传入，脚本用ARGV中的前缀拼接 job:<id> 和 unique:<key>，
+// 依赖前缀中的hash tag保证这些key与KEYS位于同一个slot，修改前缀格式时必须保留hash tag
+var (
+	enqueueScript = redis.NewScript(`
+if ARGV[7] ~= '' then
+	local existing = redis.call('GET', KEYS[3])
+	if existing then
+		return {0, existing}
+	end
+end
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return {0, ARGV[1]}
+end
+redis.call('HSET', KEYS[1], 'id', ARGV[1], 'type', ARGV[2], 'payload', ARGV[3], 'status', 'scheduled',
+	'attempts', 0, 'max_retries', ARGV[5], 'run_at', ARGV[4], 'created_at', ARGV[6], 'updated_at', ARGV[6], 'unique_key', ARGV[7])
+redis.call('ZADD', KEYS[2], ARGV[4], ARGV[1])
+if ARGV[7] ~= '' then
+	if tonumber(ARGV[8]) > 0 then
+		redis.call('SET', KEYS[3], ARGV[1], 'PX', ARGV[8])
+	else
+		redis.call('SET', KEYS[3], ARGV[1])
+	end
+end
+return {1, ARGV[1]}
+`)
+
+	// 取出一个到期任务移入running，返回任务详情；任务详情已被删除时返回0，没有到期任务返回nil
+	dequeueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+local id = ids[1]
+redis.call('ZREM', KEYS[1], id)
+local job = ARGV[3] .. 'job:' .. id
+if redis.call('EXISTS', job) == 0 then
+	return 0
+end
+redis.call('ZADD', KEYS[2], tonumber(ARGV[1]) + tonumber(ARGV[2]), id)
+redis.call('HINCRBY', job, 'attempts', 1)
+redis.call('HSET', job, 'status', 'running', 'token', ARGV[4], 'updated_at', ARGV[1])
+return redis.call('HGETALL', job)
+`)
+
+	// 执行成功，执行权已失去时返回0
+	completeScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'token') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], 'status', 'succeeded', 'token', '', 'updated_at', ARGV[3], 'finished_at', ARGV[3])
+local unique = redis.call('HGET', KEYS[2], 'unique_key')
+if unique and unique ~= '' and redis.call('GET', ARGV[5] .. 'unique:' .. unique) == ARGV[1] then
+	redis.call('DEL', ARGV[5] .. 'unique:' .. unique)
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[4])
+end
+return 1
+`)
+
+	// 执行失败，还有重试次数时按退避时间重新调度并返回1，否则标记为dead并返回0，执行权已失去时返回-1
+	failScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], 'token') ~= ARGV[2] then
+	return -1
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+local attempts = tonumber(redis.call('HGET', KEYS[3], 'attempts'))
+local max = tonumber(redis.call('HGET', KEYS[3], 'max_retries'))
+if attempts <= max then
+	local runAt = tonumber(ARGV[3]) + tonumber(ARGV[4])
+	redis.call('HSET', KEYS[3], 'status', 'scheduled', 'token', '', 'last_error', ARGV[5], 'updated_at', ARGV[3], 'run_at', runAt)
+	redis.call('ZADD', KEYS[2], runAt, ARGV[1])
+	return 1
+end
+redis.call('HSET', KEYS[3], 'status', 'dead', 'token', '', 'last_error', ARGV[5], 'updated_at', ARGV[3], 'finished_at', ARGV[3])
+local unique = redis.call('HGET', KEYS[3], 'unique_key')
+if unique and unique ~= '' and redis.call('GET', ARGV[7] .. 'unique:' .. unique) == ARGV[1] then
+	redis.call('DEL', ARGV[7] .. 'unique:' .. unique)
+end
+if tonumber(ARGV[6]) > 0 then
+	redis.call('PEXPIRE', KEYS[3], ARGV[6])
+end
+return 0
+`)
+
+	// 没有处理函数时放回队列，不计入执行次数，执行权已失去时返回0
+	releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], 'token') ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HINCRBY', KEYS[3], 'attempts', -1)
+redis.call('HSET', KEYS[3], 'status', 'scheduled', 'token', '', 'updated_at', ARGV[3], 'run_at', ARGV[4])
+redis.call('ZADD', KEYS[2], ARGV[4], ARGV[1])
+return 1
+`)
+
+	// 可见性超时的任务视为执行失败，立即重新调度或标记为dead，返回处理的任务数
+	requeueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 100)
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	local job = ARGV[2] .. 'job:' .. id
+	if redis.call('EXISTS', job) == 1 then
+		local attempts = tonumber(redis.call('HGET', job, 'attempts'))
+		local max = tonumber(redis.call('HGET', job, 'max_retries'))
+		if attempts <= max then
+			redis.call('HSET', job, 'status', 'scheduled', 'token', '', 'last_error', 'visibility timeout', 'updated_at', ARGV[1], 'run_at', ARGV[1])
+			redis.call('ZADD', KEYS[2], ARGV[1], id)
+		else
+			redis.call('HSET', job, 'status', 'dead', 'token', '', 'last_error', 'visibility timeout', 'updated_at', ARGV[1], 'finished_at', ARGV[1])
+			local unique = redis.call('HGET', job, 'unique_key')
+			if unique and unique ~= '' and redis.call('GET', ARGV[2] .. 'unique:' .. unique) == id then
+				redis.call('DEL', ARGV[2] .. 'unique:' .. unique)
+			end
+			if tonumber(ARGV[3]) > 0 then
+				redis.call('PEXPIRE', job, ARGV[3])
+			end
+		end
+	end
+end
+return #ids
+`)
+)
+
+// JobQueue 基于有序集合的延时任务队列。任务至少执行一次，可见性超时或进程退出时会重复执行，处理函数需要幂等
+type JobQueue struct {
+	client    *RedisClient
+	name      string
+	prefix    string
+	retention time.Duration
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandler
+	crons    []*cronJob
+}
+
+// JobQueueOption 队列配置项
+type JobQueueOption func(*JobQueue)
+
+// WithJobRetention 执行成功或失败的任务详情保留时间，默认24小时，0表示一直保留
+func WithJobRetention(retention time.Duration) JobQueueOption {
+	return func(q *JobQueue) {
+		q.retention = retention
+	}
+}
+
+// NewJobQueue 创建任务队列，name相同的队列共享任务
+func NewJobQueue(r *RedisClient, name string, opts ...JobQueueOption) *JobQueue {
+	q := &JobQueue{
+		client:    r,
+		name:      name,
+		prefix:    "xqueue:{" + name + "}:",
+		retention: 24 * time.Hour,
+		handlers:  make(map[string]JobHandler),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+type enqueueOptions struct {
+	id         string
+	runAt      time.Time
+	maxRetries int
+	uniqueKey  string
+	uniqueTTL  time.Duration
+}
+
+// EnqueueOption 添加任务的配置项
+type EnqueueOption func(*enqueueOptions)
+
+// WithJobID 指定任务ID，ID已存在时返回 ErrDuplicateJob，默认随机生成
+func WithJobID(id string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.id = id
+	}
+}
+
+// WithJobRunAt 指定执行时间，默认立即执行
+func WithJobRunAt(runAt time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = runAt
+	}
+}
+
+// WithJobDelay 延迟执行
+func WithJobDelay(delay time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = time.Now().Add(delay)
+	}
+}
+
+// WithJobMaxRetries 最大重试次数，默认3
+func WithJobMaxRetries(maxRetries int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxRetries = maxRetries
+	}
+}
+
+// WithJobUniqueKey 唯一任务，相同key的任务执行结束前不能重复添加；
+// ttl大于0时唯一限制最长保持ttl，否则保持到任务执行成功或失败
+func WithJobUniqueKey(key string, ttl time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = key
+		o.uniqueTTL = ttl
+	}
+}
+
+// Enqueue 添加任务并返回任务ID。唯一任务或指定的ID已存在时返回已有的任务ID和 ErrDuplicateJob
+func (q *JobQueue) Enqueue(ctx context.Context, jobType string, payload []byte, opts ...EnqueueOption) (string, error) {
+	now := time.Now()
+	o := &enqueueOptions{runAt: now, maxRetries: 3}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.id == "" {
+		id, err := newLockToken()
+		if err != nil {
+			return "", err
+		}
+		o.id = id
+	}
+	if o.maxRetries < 0 {
+		o.maxRetries = 0
+	}
+
+	keys := []string{q.jobKey(o.id), q.scheduledKey(), q.prefix + "unique:" + o.uniqueKey}
+	values, err := enqueueScript.Run(ctx, q.client.rdb, keys, o.id, jobType, payload, o.runAt.UnixMilli(),
+		o.maxRetries, now.UnixMilli(), o.uniqueKey, o.uniqueTTL.Milliseconds()).Slice()
+	q.client.logger.Info().Str("queue", q.name).Str("type", jobType).Str("id", o.id).Time("run_at", o.runAt).Any("error", err).Msg("enqueue job finish")
+	if err != nil {
+		return "", err
+	}
+	id, _ := values[1].(string)
+	if created, _ := values[0].(int64); created == 0 {
+		return id, ErrDuplicateJob
+	}
+	return id, nil
+}
+
+// EnqueueJSON 以JSON格式编码任务参数后添加任务
+func (q *JobQueue) EnqueueJSON(ctx context.Context, jobType string, v any, opts ...EnqueueOption) (string, error) {
+	payload, err := JSONCodec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return q.Enqueue(ctx, jobType, payload, opts...)
+}
+
+// GetJob 查询任务详情，任务不存在或已超过保留时间时返回 ErrJobNotFound
+func (q *JobQueue) GetJob(ctx context.Context, id string) (*Job, error) {
+	fields, err := q.client.rdb.HGetAll(ctx, q.jobKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return parseJob(fields), nil
+}
+
+// Stats 返回队列中各状态的任务数
+func (q *JobQueue) Stats(ctx context.Context) (*JobQueueStats, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	var ready, delayed, running *redis.IntCmd
+	_, err := q.client.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		ready = pipe.ZCount(ctx, q.scheduledKey(), "-inf", now)
+		delayed = pipe.ZCount(ctx, q.scheduledKey(), "("+now, "+inf")
+		running = pipe.ZCard(ctx, q.runningKey())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &JobQueueStats{Ready: ready.Val(), Delayed: delayed.Val(), Running: running.Val()}, nil
+}
+
+func (q *JobQueue) scheduledKey() string {
+	return q.prefix + "scheduled"
+}
+
+func (q *JobQueue) runningKey() string {
+	return q.prefix + "running"
+}
+
+func (q *JobQueue) jobKey(id string) string {
+	return q.prefix + "job:" + id
+}
+
+// parseJob 解析任务哈希
+func parseJob(fields map[string]string) *Job {
+	millis := func(name string) time.Time {
+		ms, err := strconv.ParseInt(fields[name], 10, 64)
+		if err != nil || ms == 0 {
+			return time.Time{}
+		}
+		return time.UnixMilli(ms)
+	}
+	attempts, _ := strconv.Atoi(fields["attempts"])
+	maxRetries, _ := strconv.Atoi(fields["max_retries"])
+	return &Job{
+		ID:         fields["id"],
+		Type:       fields["type"],
+		Payload:    []byte(fields["payload"]),
+		Status:     JobStatus(fields["status"]),
+		Attempts:   attempts,
+		MaxRetries: maxRetries,
+		RunAt:      millis("run_at"),
+		CreatedAt:  millis("created_at"),
+		UpdatedAt:  millis("updated_at"),
+		FinishedAt: millis("finished_at"),
+		LastError:  fields["last_error"],
+		UniqueKey:  fields["unique_key"],
+		token:      fields["token"],
+	}
+}
+
+// cronJob 周期任务
+type cronJob struct {
+	name     string
+	schedule cron.Schedule
+	jobType  string
+	payload  []byte
+	opts     []EnqueueOption
+}
@@ -0,0 +1,231 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runJobQueue(t *testing.T, q *JobQueue, opts ...WorkerOption) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = q.Run(ctx, append([]WorkerOption{WithPollInterval(10 * time.Millisecond)}, opts...)...)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func waitJobStatus(t *testing.T, q *JobQueue, id string, status JobStatus) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = q.GetJob(context.Background(), id)
+		return err == nil && job.Status == status
+	}, 2*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestJobQueue(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+	q := NewJobQueue(client, "mail")
+
+	type mail struct {
+		To string `json:"to"`
+	}
+	received := make(chan string, 2)
+	q.Handle("send", func(ctx context.Context, job *Job) error {
+		var m mail
+		if err := job.Decode(JSONCodec, &m); err != nil {
+			return err
+		}
+		received <- m.To
+		return nil
+	})
+
+	delayedID, err := q.EnqueueJSON(ctx, "send", mail{To: "later"}, WithJobDelay(200*time.Millisecond))
+	require.NoError(t, err)
+	id, err := q.EnqueueJSON(ctx, "send", mail{To: "now"})
+	require.NoError(t, err)
+
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &JobQueueStats{Ready: 1, Delayed: 1}, stats)
+	job, err := q.GetJob(ctx, delayedID)
+	require.NoError(t, err)
+	assert.Equal(t, JobScheduled, job.Status)
+	_, err = q.GetJob(ctx, "missing")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	runJobQueue(t, q)
+	assert.Equal(t, "now", <-received)
+	job = waitJobStatus(t, q, id, JobSucceeded)
+	assert.Equal(t, 1, job.Attempts)
+	assert.False(t, job.FinishedAt.IsZero())
+
+	select {
+	case to := <-received:
+		t.Fatalf("delayed job %s executed too early", to)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, "later", <-received)
+	waitJobStatus(t, q, delayedID, JobSucceeded)
+}
+
+func TestJobQueueRetry(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+	q := NewJobQueue(client, "retry")
+
+	var calls atomic.Int32
+	q.Handle("flaky", func(ctx context.Context, job *Job) error {
+		if calls.Add(1) < 3 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+	q.Handle("broken", func(ctx context.Context, job *Job) error {
+		panic("boom")
+	})
+
+	flakyID, err := q.Enqueue(ctx, "flaky", nil)
+	require.NoError(t, err)
+	brokenID, err := q.Enqueue(ctx, "broken", nil, WithJobMaxRetries(1))
+	require.NoError(t, err)
+	unknownID, err := q.Enqueue(ctx, "unknown", nil, WithJobMaxRetries(0))
+	require.NoError(t, err)
+
+	runJobQueue(t, q, WithRetryBackoff(func(int) time.Duration { return 10 * time.Millisecond }))
+
+	job := waitJobStatus(t, q, flakyID, JobSucceeded)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "temporary failure", job.LastError)
+
+	job = waitJobStatus(t, q, brokenID, JobDead)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Contains(t, job.LastError, "boom")
+
+	// 未注册类型的任务放回队列，不计入执行次数
+	assert.Never(t, func() bool {
+		job, err := q.GetJob(ctx, unknownID)
+		return err != nil || job.Status == JobDead || job.Attempts > 1
+	}, 200*time.Millisecond, 10*time.Millisecond)
+
+	// 由注册了该类型的实例执行
+	other := NewJobQueue(client, "retry")
+	other.Handle("unknown", func(ctx context.Context, job *Job) error {
+		return nil
+	})
+	runJobQueue(t, other)
+	job = waitJobStatus(t, q, unknownID, JobSucceeded)
+	assert.Equal(t, 1, job.Attempts)
+}
+
+func TestJobQueueUnique(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+	q := NewJobQueue(client, "unique")
+
+	id, err := q.Enqueue(ctx, "report", nil, WithJobUniqueKey("report:2024-01", 0))
+	require.NoError(t, err)
+	dup, err := q.Enqueue(ctx, "report", nil, WithJobUniqueKey("report:2024-01", 0))
+	assert.ErrorIs(t, err, ErrDuplicateJob)
+	assert.Equal(t, id, dup)
+
+	_, err = q.Enqueue(ctx, "report", nil, WithJobID(id))
+	assert.ErrorIs(t, err, ErrDuplicateJob)
+
+	// 执行结束后可以再次添加
+	q.Handle("report", func(ctx context.Context, job *Job) error { return nil })
+	runJobQueue(t, q)
+	waitJobStatus(t, q, id, JobSucceeded)
+	assert.False(t, mr.Exists("xqueue:{unique}:unique:report:2024-01"))
+	_, err = q.Enqueue(ctx, "report", nil, WithJobUniqueKey("report:2024-01", 0))
+	require.NoError(t, err)
+}
+
+func TestJobQueueVisibilityTimeout(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+	q := NewJobQueue(client, "visibility", WithJobRetention(time.Minute))
+
+	id, err := q.Enqueue(ctx, "slow", nil, WithJobMaxRetries(1))
+	require.NoError(t, err)
+	job, err := q.dequeue(ctx, 20*time.Millisecond)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, JobRunning, job.Status)
+
+	// 超时后重新调度，原执行者不能再确认结果
+	time.Sleep(30 * time.Millisecond)
+	q.requeueExpired(ctx)
+	current, err := q.GetJob(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, JobScheduled, current.Status)
+	assert.Equal(t, "visibility timeout", current.LastError)
+	q.complete(ctx, job, time.Now())
+	current, err = q.GetJob(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, JobScheduled, current.Status)
+
+	// 重试次数用完后标记为dead
+	_, err = q.dequeue(ctx, 20*time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	q.requeueExpired(ctx)
+	current, err = q.GetJob(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, JobDead, current.Status)
+	assert.Equal(t, 2, current.Attempts)
+}
+
+func TestJobQueueCron(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+	q := NewJobQueue(client, "cron")
+	require.Error(t, q.Cron("bad", "not a spec", "cleanup", nil))
+	require.NoError(t, q.Cron("cleanup", "*/5 * * * *", "cleanup", []byte("all")))
+	o := &workerOptions{poll: time.Second}
+
+	// 第一次只记录开始时间
+	q.scheduleCrons(ctx, o)
+	stats, err := q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), stats.Ready+stats.Delayed)
+
+	// 错过多次执行时只补最近一次，重复调度不会重复添加
+	start := time.Date(2024, 1, 1, 10, 1, 0, 0, time.Local)
+	require.NoError(t, client.Client().Set(ctx, q.prefix+"cron:cleanup", start.UnixMilli(), 0).Err())
+	now := time.Date(2024, 1, 1, 10, 17, 0, 0, time.Local)
+	c := q.crons[0]
+	require.NoError(t, q.scheduleCron(ctx, c, now))
+	require.NoError(t, q.scheduleCron(ctx, c, now))
+	stats, err = q.Stats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Ready)
+	due := time.Date(2024, 1, 1, 10, 15, 0, 0, time.Local)
+	job, err := q.GetJob(ctx, "cron:cleanup:"+strconv.FormatInt(due.UnixMilli(), 10))
+	require.NoError(t, err)
+	assert.Equal(t, "cleanup", job.Type)
+	assert.Equal(t, "all", string(job.Payload))
+
+	// 其他实例持有锁时不调度
+	lock, err := client.AcquireLock(ctx, q.prefix+"cron:lock", WithLockNoWait())
+	require.NoError(t, err)
+	defer lock.Unlock(ctx)
+	require.NoError(t, client.Client().Set(ctx, q.prefix+"cron:cleanup", start.UnixMilli(), 0).Err())
+	q.scheduleCrons(ctx, o)
+	last, err := client.Client().Get(ctx, q.prefix+"cron:cleanup").Int64()
+	require.NoError(t, err)
+	assert.Equal(t, start.UnixMilli(), last)
+}
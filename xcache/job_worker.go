@@ -0,0 +1,320 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/robfig/cron/v3"
+)
+
+// JobHandler 任务处理函数，返回错误时按退避时间重试
+type JobHandler func(ctx context.Context, job *Job) error
+
+// Handle 注册任务类型的处理函数，需要在 Run 之前调用。
+// 实例取到未注册类型的任务时放回队列，不计入执行次数，由注册了该类型的实例执行
+func (q *JobQueue) Handle(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Cron 注册周期任务，spec为标准的5段cron表达式或 @every 1h 等描述符。
+// 所有实例都可以注册，Run 时通过分布式锁保证同一时刻只有一个实例添加任务；
+// 停机期间错过的执行只补一次
+func (q *JobQueue) Cron(name, spec, jobType string, payload []byte, opts ...EnqueueOption) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("parse cron spec %q failed: %w", spec, err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.crons = append(q.crons, &cronJob{name: name, schedule: schedule, jobType: jobType, payload: payload, opts: opts})
+	return nil
+}
+
+type workerOptions struct {
+	concurrency int
+	visibility  time.Duration
+	poll        time.Duration
+	backoff     func(attempts int) time.Duration
+}
+
+// WorkerOption 执行任务的配置项
+type WorkerOption func(*workerOptions)
+
+// WithWorkerConcurrency 并发执行的任务数，默认10
+func WithWorkerConcurrency(n int) WorkerOption {
+	return func(o *workerOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithVisibilityTimeout 任务执行超时时间，超时后任务的ctx被取消，并由其他实例重新执行，默认5分钟
+func WithVisibilityTimeout(timeout time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		o.visibility = timeout
+	}
+}
+
+// WithPollInterval 没有到期任务时的轮询间隔，也是检查超时任务和周期任务的间隔，默认1秒
+func WithPollInterval(interval time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		o.poll = interval
+	}
+}
+
+// WithRetryBackoff 第attempts次执行失败后的重试等待时间，默认从1秒开始指数增长，最长1小时
+func WithRetryBackoff(backoff func(attempts int) time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		o.backoff = backoff
+	}
+}
+
+// defaultRetryBackoff 指数退避并增加最多20%的随机时间
+func defaultRetryBackoff(attempts int) time.Duration {
+	d := time.Hour
+	if attempts < 13 {
+		d = min(time.Second<<(attempts-1), time.Hour)
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5+1))
+}
+
+// Run 执行任务直到ctx结束，ctx结束后等待执行中的任务完成再返回
+func (q *JobQueue) Run(ctx context.Context, opts ...WorkerOption) error {
+	o := &workerOptions{
+		concurrency: 10,
+		visibility:  5 * time.Minute,
+		poll:        time.Second,
+		backoff:     defaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.concurrency <= 0 {
+		o.concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < o.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, o)
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.maintain(ctx, o)
+	}()
+	wg.Wait()
+	return ctx.Err()
+}
+
+// work 循环取出并执行到期任务
+func (q *JobQueue) work(ctx context.Context, o *workerOptions) {
+	for ctx.Err() == nil {
+		job, err := q.dequeue(ctx, o.visibility)
+		if err != nil && ctx.Err() == nil {
+			q.client.logger.Error().Str("queue", q.name).Err(err).Msg("dequeue job failed")
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(o.poll):
+			}
+			continue
+		}
+		q.execute(ctx, job, o)
+	}
+}
+
+// maintain 定期重新调度超时任务并添加周期任务
+func (q *JobQueue) maintain(ctx context.Context, o *workerOptions) {
+	ticker := time.NewTicker(o.poll)
+	defer ticker.Stop()
+	for {
+		q.requeueExpired(ctx)
+		q.scheduleCrons(ctx, o)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dequeue 取出一个到期任务，没有到期任务时返回nil
+func (q *JobQueue) dequeue(ctx context.Context, visibility time.Duration) (*Job, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		res, err := dequeueScript.Run(ctx, q.client.rdb, []string{q.scheduledKey(), q.runningKey()},
+			time.Now().UnixMilli(), visibility.Milliseconds(), q.prefix, token).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values, ok := res.([]any)
+		if !ok {
+			// 任务详情已被删除，继续取下一个
+			continue
+		}
+		fields := make(map[string]string, len(values)/2)
+		for i := 0; i+1 < len(values); i += 2 {
+			k, _ := values[i].(string)
+			v, _ := values[i+1].(string)
+			fields[k] = v
+		}
+		return parseJob(fields), nil
+	}
+}
+
+// execute 执行任务并记录结果，执行不受Run的ctx取消影响，超过可见性超时时间后取消
+func (q *JobQueue) execute(ctx context.Context, job *Job, o *workerOptions) {
+	q.mu.RLock()
+	handler := q.handlers[job.Type]
+	q.mu.RUnlock()
+	if handler == nil {
+		q.release(context.WithoutCancel(ctx), job, o.poll)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), o.visibility)
+	defer cancel()
+	st := time.Now()
+	err := runJob(jobCtx, handler, job)
+	resultCtx := context.WithoutCancel(ctx)
+	if err == nil {
+		q.complete(resultCtx, job, st)
+		return
+	}
+	q.fail(resultCtx, job, err, o.backoff(job.Attempts), st)
+}
+
+// runJob 调用处理函数，panic转为错误
+func runJob(ctx context.Context, handler JobHandler, job *Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panic: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+func (q *JobQueue) complete(ctx context.Context, job *Job, st time.Time) {
+	n, err := completeScript.Run(ctx, q.client.rdb, []string{q.runningKey(), q.jobKey(job.ID)},
+		job.ID, job.token, time.Now().UnixMilli(), q.retention.Milliseconds(), q.prefix).Int64()
+	switch {
+	case err != nil:
+		q.client.logger.Error().Str("queue", q.name).Str("id", job.ID).Err(err).Msg("complete job failed")
+	case n == 0:
+		q.client.logger.Warn().Str("queue", q.name).Str("id", job.ID).Msg("job finished after visibility timeout")
+	default:
+		q.client.logger.Info().Str("queue", q.name).Str("type", job.Type).Str("id", job.ID).Int("attempts", job.Attempts).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("job succeeded")
+	}
+}
+
+func (q *JobQueue) fail(ctx context.Context, job *Job, jobErr error, backoff time.Duration, st time.Time) {
+	n, err := failScript.Run(ctx, q.client.rdb, []string{q.runningKey(), q.scheduledKey(), q.jobKey(job.ID)},
+		job.ID, job.token, time.Now().UnixMilli(), backoff.Milliseconds(), jobErr.Error(), q.retention.Milliseconds(), q.prefix).Int64()
+	event := q.client.logger.Error().Str("queue", q.name).Str("type", job.Type).Str("id", job.ID).Int("attempts", job.Attempts).
+		Int("cost(ms)", int(time.Since(st).Milliseconds())).AnErr("job_error", jobErr)
+	switch {
+	case err != nil:
+		event.Err(err).Msg("record job failure failed")
+	case n < 0:
+		event.Msg("job failed after visibility timeout")
+	case n == 0:
+		event.Msg("job failed and retries exhausted")
+	default:
+		event.Dur("retry_in", backoff).Msg("job failed, will retry")
+	}
+}
+
+// release 把没有处理函数的任务放回队列，delay后可以再次被取出
+func (q *JobQueue) release(ctx context.Context, job *Job, delay time.Duration) {
+	now := time.Now()
+	n, err := releaseScript.Run(ctx, q.client.rdb, []string{q.runningKey(), q.scheduledKey(), q.jobKey(job.ID)},
+		job.ID, job.token, now.UnixMilli(), now.Add(delay).UnixMilli()).Int64()
+	switch {
+	case err != nil:
+		q.client.logger.Error().Str("queue", q.name).Str("type", job.Type).Str("id", job.ID).Err(err).Msg("release job failed")
+	case n > 0:
+		q.client.logger.Warn().Str("queue", q.name).Str("type", job.Type).Str("id", job.ID).Msg("no handler for job type, released")
+	}
+}
+
+func (q *JobQueue) requeueExpired(ctx context.Context) {
+	n, err := requeueScript.Run(ctx, q.client.rdb, []string{q.runningKey(), q.scheduledKey()},
+		time.Now().UnixMilli(), q.prefix, q.retention.Milliseconds()).Int64()
+	if err != nil && ctx.Err() == nil {
+		q.client.logger.Error().Str("queue", q.name).Err(err).Msg("requeue expired jobs failed")
+	}
+	if n > 0 {
+		q.client.logger.Warn().Str("queue", q.name).Int64("count", n).Msg("requeued jobs after visibility timeout")
+	}
+}
+
+// scheduleCrons 持有锁的实例添加到期的周期任务，任务ID由名称和执行时间组成，重复添加会被忽略
+func (q *JobQueue) scheduleCrons(ctx context.Context, o *workerOptions) {
+	q.mu.RLock()
+	crons := q.crons
+	q.mu.RUnlock()
+	if len(crons) == 0 {
+		return
+	}
+	lock, err := q.client.AcquireLock(ctx, q.prefix+"cron:lock", WithLockTTL(o.poll+30*time.Second), WithLockNoWait())
+	if err != nil {
+		if !errors.Is(err, ErrLockNotAcquired) && ctx.Err() == nil {
+			q.client.logger.Error().Str("queue", q.name).Err(err).Msg("acquire cron lock failed")
+		}
+		return
+	}
+	defer func() {
+		_ = lock.Unlock(context.WithoutCancel(ctx))
+	}()
+
+	now := time.Now()
+	for _, c := range crons {
+		if err := q.scheduleCron(ctx, c, now); err != nil && ctx.Err() == nil {
+			q.client.logger.Error().Str("queue", q.name).Str("cron", c.name).Err(err).Msg("schedule cron job failed")
+		}
+	}
+}
+
+func (q *JobQueue) scheduleCron(ctx context.Context, c *cronJob, now time.Time) error {
+	key := q.prefix + "cron:" + c.name
+	last, err := q.client.rdb.Get(ctx, key).Int64()
+	if errors.Is(err, redis.Nil) {
+		// 第一次注册时从当前时间开始计算
+		return q.client.rdb.Set(ctx, key, now.UnixMilli(), 0).Err()
+	}
+	if err != nil {
+		return err
+	}
+	next := c.schedule.Next(time.UnixMilli(last))
+	if next.After(now) {
+		return nil
+	}
+	for n := c.schedule.Next(next); !n.After(now); n = c.schedule.Next(n) {
+		next = n
+	}
+
+	id := "cron:" + c.name + ":" + strconv.FormatInt(next.UnixMilli(), 10)
+	opts := append(append([]EnqueueOption{}, c.opts...), WithJobID(id), WithJobRunAt(next))
+	if _, err := q.Enqueue(ctx, c.jobType, c.payload, opts...); err != nil && !errors.Is(err, ErrDuplicateJob) {
+		return err
+	}
+	return q.client.rdb.Set(ctx, key, next.UnixMilli(), 0).Err()
+}
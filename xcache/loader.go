@@ -0,0 +1,207 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound 数据不存在，loader返回该错误时会缓存空结果
+var ErrNotFound = errors.New("not found")
+
+// loadGroup 合并同一进程内对相同key的并发回源
+var loadGroup singleflight.Group
+
+// loadEntry 缓存的数据和用于提前刷新的元数据
+type loadEntry[T any] struct {
+	Value    T     `json:"v" msgpack:"v"`
+	NotFound bool  `json:"nf,omitempty" msgpack:"nf,omitempty"`
+	ExpireAt int64 `json:"exp" msgpack:"exp"` // 过期时间，毫秒时间戳
+	Delta    int64 `json:"d" msgpack:"d"`     // 回源耗时，毫秒
+}
+
+type loadOptions struct {
+	codec       Codec
+	negativeTTL time.Duration
+	jitter      float64
+	beta        float64
+	timeout     time.Duration
+	isNotFound  func(error) bool
+}
+
+// LoadOption GetOrLoad 配置项
+type LoadOption func(*loadOptions)
+
+// WithLoadCodec 缓存的编解码方式，默认 JSONCodec
+func WithLoadCodec(codec Codec) LoadOption {
+	return func(o *loadOptions) {
+		o.codec = codec
+	}
+}
+
+// WithNegativeTTL 空结果的缓存时间，默认30秒，小于等于0表示不缓存空结果
+func WithNegativeTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithTTLJitter 过期时间随机增加的比例，避免大量key同时过期，默认0.1
+func WithTTLJitter(jitter float64) LoadOption {
+	return func(o *loadOptions) {
+		o.jitter = jitter
+	}
+}
+
+// WithEarlyRefresh 概率提前刷新的系数，越大越早刷新，默认1，0表示不提前刷新
+func WithEarlyRefresh(beta float64) LoadOption {
+	return func(o *loadOptions) {
+		o.beta = beta
+	}
+}
+
+// WithLoadTimeout 回源的超时时间，默认10秒。回源由并发的调用方共享，不受单个调用方取消的影响
+func WithLoadTimeout(timeout time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithNotFound 判断loader返回的错误是否表示数据不存在，如 gorm.ErrRecordNotFound，默认只识别 ErrNotFound
+func WithNotFound(isNotFound func(error) bool) LoadOption {
+	return func(o *loadOptions) {
+		o.isNotFound = isNotFound
+	}
+}
+
+// GetOrLoad 读取缓存，未命中时调用loader回源并写入缓存，ttl为缓存时间。
+// 同一进程内相同key的并发回源会合并；数据不存在时缓存空结果并返回 ErrNotFound；
+// 接近过期时按概率提前在后台刷新（XFetch算法），避免缓存过期瞬间大量请求回源；loader的panic转为错误返回
+func GetOrLoad[T any](ctx context.Context, r *RedisClient, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...LoadOption) (T, error) {
+	o := &loadOptions{
+		codec:       JSONCodec,
+		negativeTTL: 30 * time.Second,
+		jitter:      0.1,
+		beta:        1,
+		timeout:     10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.timeout <= 0 {
+		o.timeout = 10 * time.Second
+	}
+	if o.isNotFound == nil {
+		o.isNotFound = func(err error) bool { return errors.Is(err, ErrNotFound) }
+	}
+
+	var zero T
+	// key相同但类型不同的调用方不能共享回源结果，reflect.Type 的地址在进程内唯一标识类型
+	groupKey := fmt.Sprintf("%p:%p:%s", r, reflect.TypeOf((*T)(nil)).Elem(), key)
+	load := func() (any, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), o.timeout)
+		defer cancel()
+		return loadAndStore(loadCtx, r, key, ttl, loader, o)
+	}
+
+	entry, err := GetAs[loadEntry[T]](ctx, r, o.codec, key)
+	if err == nil {
+		if o.beta > 0 && shouldRefreshEarly(entry.ExpireAt, entry.Delta, o.beta) {
+			// 后台刷新，当前请求直接返回缓存
+			loadGroup.DoChan(groupKey, load)
+		}
+		if entry.NotFound {
+			return zero, ErrNotFound
+		}
+		return entry.Value, nil
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		// 缓存不可用或数据无法解码时直接回源
+		r.logger.Error().Str("key", key).Err(err).Msg("get cache failed, load from source")
+	}
+
+	// 共享的回源不受单个调用方取消的影响，调用方取消时直接返回
+	select {
+	case res := <-loadGroup.DoChan(groupKey, load):
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// T为接口类型时nil值无法断言
+		if res.Val == nil {
+			return zero, nil
+		}
+		v, ok := res.Val.(T)
+		if !ok {
+			return zero, fmt.Errorf("load %s: unexpected type %T", key, res.Val)
+		}
+		return v, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// loadAndStore 回源并写入缓存
+func loadAndStore[T any](ctx context.Context, r *RedisClient, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), o *loadOptions) (T, error) {
+	start := time.Now()
+	value, err := callLoader(ctx, key, loader)
+	delta := time.Since(start)
+
+	entry := loadEntry[T]{Value: value, Delta: delta.Milliseconds()}
+	switch {
+	case err == nil:
+		ttl = jitterTTL(ttl, o.jitter)
+	case o.isNotFound(err) && o.negativeTTL > 0:
+		entry.NotFound = true
+		ttl = o.negativeTTL
+		err = ErrNotFound
+	case o.isNotFound(err):
+		return value, ErrNotFound
+	default:
+		return value, err
+	}
+	// 不过期的缓存不需要提前刷新
+	if ttl > 0 {
+		entry.ExpireAt = time.Now().Add(ttl).UnixMilli()
+	}
+	if setErr := SetAs(ctx, r, o.codec, key, entry, ttl); setErr != nil {
+		r.logger.Error().Str("key", key).Err(setErr).Msg("set cache failed")
+	}
+	return value, err
+}
+
+// callLoader 调用loader，panic转为错误，避免在singleflight中panic导致进程退出
+func callLoader[T any](ctx context.Context, key string, loader func(ctx context.Context) (T, error)) (value T, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("load %s panic: %v", key, p)
+		}
+	}()
+	return loader(ctx)
+}
+
+// jitterTTL 在ttl基础上随机增加 [0, ttl*jitter) 的时间
+func jitterTTL(ttl time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 || ttl <= 0 {
+		return ttl
+	}
+	return ttl + time.Duration(rand.Float64()*jitter*float64(ttl))
+}
+
+// shouldRefreshEarly XFetch：now - delta*beta*ln(rand) >= expireAt 时提前刷新，
+// 回源越慢、越接近过期，提前刷新的概率越大
+func shouldRefreshEarly(expireAt, delta int64, beta float64) bool {
+	if expireAt <= 0 {
+		return false
+	}
+	if delta <= 0 {
+		delta = 1
+	}
+	now := float64(time.Now().UnixMilli())
+	return now-float64(delta)*beta*math.Log(1-rand.Float64()) >= float64(expireAt)
+}
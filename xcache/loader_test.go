@@ -0,0 +1,141 @@
+package xcache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return "value", nil
+	}
+
+	// 并发未命中只回源一次
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := GetOrLoad(ctx, client, "k", time.Minute, loader, WithEarlyRefresh(0))
+			assert.NoError(t, err)
+			assert.Equal(t, "value", v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+
+	// 过期时间增加了随机抖动
+	ttl := mr.TTL("k")
+	assert.GreaterOrEqual(t, ttl, time.Minute)
+	assert.Less(t, ttl, time.Minute+6*time.Second)
+
+	v, err := GetOrLoad(ctx, client, "k", time.Minute, loader, WithEarlyRefresh(0))
+	require.NoError(t, err)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestGetOrLoadNotFound(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	loader := func(ctx context.Context) (*struct{ Name string }, error) {
+		calls.Add(1)
+		return nil, ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		_, err := GetOrLoad(ctx, client, "missing", time.Minute, loader, WithNegativeTTL(5*time.Second))
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, 5*time.Second, mr.TTL("missing"))
+}
+
+func TestGetOrLoadEarlyRefresh(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	loader := func(ctx context.Context) (int32, error) {
+		n := calls.Add(1)
+		if n > 1 {
+			refreshed <- struct{}{}
+		}
+		return n, nil
+	}
+
+	v, err := GetOrLoad(ctx, client, "counter", time.Minute, loader)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	// 系数足够大时必然提前刷新，当前请求仍返回旧值
+	v, err = GetOrLoad(ctx, client, "counter", time.Minute, loader, WithEarlyRefresh(1e9))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), v)
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("cache was not refreshed")
+	}
+	assert.Eventually(t, func() bool {
+		v, err := GetJSON[loadEntry[int32]](ctx, client, "counter")
+		return err == nil && v.Value == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestGetOrLoadFailures(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	// loader的panic转为错误，不缓存
+	_, err := GetOrLoad(ctx, client, "panic", time.Minute, func(ctx context.Context) (string, error) {
+		panic("boom")
+	})
+	assert.ErrorContains(t, err, "panic: boom")
+	assert.False(t, mr.Exists("panic"))
+
+	// 共享的回源有超时时间
+	_, err = GetOrLoad(ctx, client, "slow", time.Minute, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, WithLoadTimeout(20*time.Millisecond))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 相同key不同类型的并发回源不合并
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err := GetOrLoad(ctx, client, "typed", time.Minute, func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "value", nil
+		}, WithEarlyRefresh(0))
+		assert.NoError(t, err)
+		assert.Equal(t, "value", v)
+	}()
+	<-started
+	n, err := GetOrLoad(ctx, client, "typed", time.Minute, func(ctx context.Context) (int, error) {
+		return 1, nil
+	}, WithEarlyRefresh(0))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	close(release)
+	<-done
+}
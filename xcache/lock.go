@@ -0,0 +1,286 @@
+package xcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 锁相关的错误
+var (
+	// ErrLockNotAcquired 锁已被占用或等待超时，可以用 errors.Is 判断
+	ErrLockNotAcquired = errors.New("lock not acquired")
+	// ErrLockNotHeld 锁已过期或被其他持有者获取
+	ErrLockNotHeld = errors.New("lock not held")
+	// ErrLockLost WithLock 执行期间锁续期失败，互斥已无法保证
+	ErrLockLost = errors.New("lock lost")
+
+	errInvalidLockTTL = errors.New("lock ttl must be positive")
+)
+
+// Lock 已获取的锁
+type Lock interface {
+	// Key 锁的key
+	Key() string
+	// Unlock 释放锁，锁已过期或被其他持有者获取时返回 ErrLockNotHeld
+	Unlock(ctx context.Context) error
+	// Extend 将锁的过期时间重置为ttl，锁已过期或被其他持有者获取时返回 ErrLockNotHeld
+	Extend(ctx context.Context, ttl time.Duration) error
+}
+
+// 锁保存为hash，field为持有者，value为重入次数
+var (
+	// 不存在或由同一持有者持有时加锁，返回重入次数，被占用返回0
+	acquireScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 or redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	local n = redis.call("HINCRBY", KEYS[1], ARGV[1], 1)
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return n
+end
+return 0`)
+	// 重入次数减一，减到0时删除，返回剩余次数，不是持有者返回-1
+	unlockScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 0 then
+	return -1
+end
+local n = redis.call("HINCRBY", KEYS[1], ARGV[1], -1)
+if n <= 0 then
+	redis.call("DEL", KEYS[1])
+end
+return n`)
+	// 只延长自己持有的锁
+	extendScript = redis.NewScript(`
+if redis.call("HEXISTS", KEYS[1], ARGV[1]) == 1 then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+type lockOptions struct {
+	ttl           time.Duration
+	owner         string
+	wait          time.Duration
+	noWait        bool
+	retryInterval time.Duration
+}
+
+// LockOption 加锁配置项
+type LockOption func(*lockOptions)
+
+// WithLockTTL 锁的过期时间，默认30秒。WithLock 会在执行期间自动续期
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithLockOwner 锁的持有者，相同持有者可以重入，每次加锁都需要对应一次释放。
+// 默认每次加锁生成随机持有者，即不可重入
+func WithLockOwner(owner string) LockOption {
+	return func(o *lockOptions) {
+		o.owner = owner
+	}
+}
+
+// WithLockWaitTimeout 等待锁的最长时间，默认一直等待到ctx结束
+func WithLockWaitTimeout(wait time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.wait = wait
+	}
+}
+
+// WithLockNoWait 只尝试一次，锁被占用时立即返回 ErrLockNotAcquired
+func WithLockNoWait() LockOption {
+	return func(o *lockOptions) {
+		o.noWait = true
+	}
+}
+
+// WithLockRetryInterval 等待锁时的重试间隔，默认100毫秒
+func WithLockRetryInterval(interval time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.retryInterval = interval
+	}
+}
+
+func newLockOptions(opts []LockOption) (*lockOptions, error) {
+	o := &lockOptions{
+		ttl:           30 * time.Second,
+		retryInterval: 100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.ttl <= 0 {
+		return nil, errInvalidLockTTL
+	}
+	if o.retryInterval <= 0 {
+		o.retryInterval = 100 * time.Millisecond
+	}
+	if o.owner == "" {
+		owner, err := newLockToken()
+		if err != nil {
+			return nil, err
+		}
+		o.owner = owner
+	}
+	return o, nil
+}
+
+// newLockToken 生成锁的持有者标识
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// TryLock 非阻塞加锁，锁已被占用时返回 ErrLockNotAcquired
+func (r *RedisClient) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	return r.AcquireLock(ctx, key, WithLockTTL(ttl), WithLockNoWait())
+}
+
+// AcquireLock 加锁，锁被占用时按间隔重试，直到获取成功、ctx结束或等待超时，
+// 未获取到锁时返回 ErrLockNotAcquired
+func (r *RedisClient) AcquireLock(ctx context.Context, key string, opts ...LockOption) (Lock, error) {
+	o, err := newLockOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.wait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.wait)
+		defer cancel()
+	}
+
+	st := time.Now()
+	lock := &redisLock{client: r, key: key, owner: o.owner}
+	for {
+		n, err := acquireScript.Run(ctx, r.rdb, []string{key}, o.owner, o.ttl.Milliseconds()).Int64()
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error().Str("key", key).Err(err).Msg("acquire lock failed")
+			return nil, err
+		}
+		if err == nil && n > 0 {
+			r.logger.Info().Str("key", key).Int64("count", n).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("acquire lock finish")
+			return lock, nil
+		}
+		if o.noWait {
+			return nil, fmt.Errorf("%w: %s", ErrLockNotAcquired, key)
+		}
+		select {
+		case <-ctx.Done():
+			r.logger.Info().Str("key", key).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("acquire lock timeout")
+			return nil, fmt.Errorf("%w: %s: %v", ErrLockNotAcquired, key, ctx.Err())
+		case <-time.After(o.retryInterval):
+		}
+	}
+}
+
+// WithLock 加锁后执行fn，执行期间每隔ttl/3自动续期，fn返回或panic后释放锁，panic会继续向上抛出。
+// 续期失败时取消传给fn的ctx，fn未返回错误时 WithLock 返回 ErrLockLost
+func (r *RedisClient) WithLock(ctx context.Context, key string, fn func(ctx context.Context) error, opts ...LockOption) (err error) {
+	o, err := newLockOptions(opts)
+	if err != nil {
+		return err
+	}
+	// 同一次调用的加锁和续期使用相同的持有者
+	opts = append(opts, WithLockOwner(o.owner))
+	lock, err := r.AcquireLock(ctx, key, opts...)
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.watchLock(fnCtx, lock, o.ttl, stop, cancel)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+		lost := errors.Is(context.Cause(fnCtx), ErrLockLost)
+		cancel(nil)
+		// 调用方的ctx可能已经结束，释放锁不受其影响
+		unlockCtx, unlockCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer unlockCancel()
+		if unlockErr := lock.Unlock(unlockCtx); unlockErr != nil && !errors.Is(unlockErr, ErrLockNotHeld) {
+			r.logger.Error().Str("key", key).Err(unlockErr).Msg("unlock failed")
+		}
+		if err == nil && lost {
+			err = ErrLockLost
+		}
+	}()
+	return fn(fnCtx)
+}
+
+// watchLock 定期续期，锁已丢失或超过ttl仍未续期成功时以 ErrLockLost 取消ctx
+func (r *RedisClient) watchLock(ctx context.Context, lock Lock, ttl time.Duration, stop <-chan struct{}, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	lastExtended := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := lock.Extend(ctx, ttl)
+		if err == nil {
+			lastExtended = time.Now()
+			continue
+		}
+		r.logger.Error().Str("key", lock.Key()).Err(err).Msg("extend lock failed")
+		if errors.Is(err, ErrLockNotHeld) || time.Since(lastExtended) >= ttl {
+			cancel(ErrLockLost)
+			return
+		}
+	}
+}
+
+// redisLock 可重入的单节点锁
+type redisLock struct {
+	client *RedisClient
+	key    string
+	owner  string
+}
+
+func (l *redisLock) Key() string {
+	return l.key
+}
+
+func (l *redisLock) Unlock(ctx context.Context) error {
+	n, err := unlockScript.Run(ctx, l.client.rdb, []string{l.key}, l.owner).Int64()
+	l.client.logger.Info().Str("key", l.key).Int64("count", n).Any("error", err).Msg("unlock finish")
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *redisLock) Extend(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errInvalidLockTTL
+	}
+	n, err := extendScript.Run(ctx, l.client.rdb, []string{l.key}, l.owner, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLock(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	lock, err := client.TryLock(ctx, "lock", time.Minute)
+	require.NoError(t, err)
+	_, err = client.TryLock(ctx, "lock", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// 等待超时
+	start := time.Now()
+	_, err = client.AcquireLock(ctx, "lock", WithLockWaitTimeout(100*time.Millisecond), WithLockRetryInterval(20*time.Millisecond))
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	// 锁释放后等待方获取成功
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = lock.Unlock(ctx)
+	}()
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	lock, err = client.AcquireLock(waitCtx, "lock", WithLockRetryInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+func TestLockReentrant(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	outer, err := client.AcquireLock(ctx, "lock", WithLockOwner("job-1"), WithLockNoWait())
+	require.NoError(t, err)
+	inner, err := client.AcquireLock(ctx, "lock", WithLockOwner("job-1"), WithLockNoWait())
+	require.NoError(t, err)
+	_, err = client.AcquireLock(ctx, "lock", WithLockOwner("job-2"), WithLockNoWait())
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	// 释放次数与加锁次数一致后才真正释放
+	require.NoError(t, inner.Unlock(ctx))
+	assert.True(t, mr.Exists("lock"))
+	require.NoError(t, outer.Unlock(ctx))
+	assert.False(t, mr.Exists("lock"))
+	assert.ErrorIs(t, outer.Unlock(ctx), ErrLockNotHeld)
+}
+
+func TestWithLock(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	t.Run("watchdog", func(t *testing.T) {
+		err := client.WithLock(ctx, "lock", func(ctx context.Context) error {
+			// 总时间超过ttl，续期后锁仍然存在
+			for i := 0; i < 3; i++ {
+				mr.FastForward(200 * time.Millisecond)
+				time.Sleep(150 * time.Millisecond)
+			}
+			assert.True(t, mr.Exists("lock"))
+			return ctx.Err()
+		}, WithLockTTL(300*time.Millisecond))
+		require.NoError(t, err)
+		assert.False(t, mr.Exists("lock"))
+	})
+
+	t.Run("lock lost", func(t *testing.T) {
+		err := client.WithLock(ctx, "lock", func(ctx context.Context) error {
+			mr.Del("lock")
+			select {
+			case <-ctx.Done():
+				assert.ErrorIs(t, context.Cause(ctx), ErrLockLost)
+			case <-time.After(time.Second):
+				t.Error("context not canceled after lock lost")
+			}
+			return nil
+		}, WithLockTTL(150*time.Millisecond))
+		assert.ErrorIs(t, err, ErrLockLost)
+	})
+
+	t.Run("business error", func(t *testing.T) {
+		businessErr := errors.New("business")
+		err := client.WithLock(ctx, "lock", func(ctx context.Context) error {
+			return businessErr
+		})
+		assert.ErrorIs(t, err, businessErr)
+		assert.False(t, mr.Exists("lock"))
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = client.WithLock(ctx, "lock", func(ctx context.Context) error {
+				panic("boom")
+			})
+		})
+		assert.False(t, mr.Exists("lock"))
+	})
+
+	t.Run("busy", func(t *testing.T) {
+		lock, err := client.TryLock(ctx, "lock", time.Minute)
+		require.NoError(t, err)
+		defer lock.Unlock(ctx)
+		called := false
+		err = client.WithLock(ctx, "lock", func(ctx context.Context) error {
+			called = true
+			return nil
+		}, WithLockNoWait())
+		assert.ErrorIs(t, err, ErrLockNotAcquired)
+		assert.False(t, called)
+	})
+}
+
+func TestRedLockFunc(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = client.RedLockFunc("redlock", func() error {
+			panic("boom")
+		})
+	})
+	// panic后锁已释放
+	assert.False(t, mr.Exists("redlock"))
+
+	mutex := client.NewMutex("redlock")
+	require.NoError(t, mutex.Lock())
+	err := client.RedLockFunc("redlock", func() error { return nil }, redsync.WithTries(1))
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+}
+
+func TestNewMutexConcurrent(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	shadow := &RedisClient{rdb: client.Client(), logger: client.logger}
+
+	// 并发创建锁时分布式锁对象只初始化一次
+	var wg sync.WaitGroup
+	mutexes := make([]*redsync.Mutex, 10)
+	for i := range mutexes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mutexes[i] = shadow.NewMutex("concurrent")
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, mutexes[0].Lock())
+	assert.Error(t, mutexes[1].TryLock())
+}
@@ -0,0 +1,109 @@
+package xcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruEntry 本地缓存条目
+type lruEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+func (e *lruEntry) cost() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// lruCache 按条目数和字节数限制容量、支持过期时间的LRU缓存
+type lruCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	ll         *list.List
+	items      map[string]*list.Element
+	evictions  int64
+	now        func() time.Time
+}
+
+func newLRUCache(maxEntries int, maxBytes int64) *lruCache {
+	return &lruCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *lruCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*lruEntry)
+	if !entry.expireAt.IsZero() && c.now().After(entry.expireAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.value, true
+}
+
+func (c *lruCache) set(key string, value []byte, ttl time.Duration) {
+	entry := &lruEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expireAt = c.now().Add(ttl)
+	}
+	// 单个条目超过容量时不缓存
+	if c.maxBytes > 0 && entry.cost() > c.maxBytes {
+		c.delete(key)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+	c.items[key] = c.ll.PushFront(entry)
+	c.bytes += entry.cost()
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.removeElement(c.ll.Back())
+		c.evictions++
+	}
+}
+
+func (c *lruCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *lruCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+	c.bytes = 0
+}
+
+// stats 返回条目数、字节数和淘汰次数
+func (c *lruCache) stats() (int, int64, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len(), c.bytes, c.evictions
+}
+
+func (c *lruCache) removeElement(el *list.Element) {
+	entry := c.ll.Remove(el).(*lruEntry)
+	delete(c.items, entry.key)
+	c.bytes -= entry.cost()
+}
@@ -0,0 +1,310 @@
+package xcache
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval 每写入多少次清理一次过期key
+const memorySweepInterval = 1024
+
+// memoryItem 内存缓存条目，expireAt为零值表示不过期
+type memoryItem struct {
+	value    []byte
+	expireAt time.Time
+}
+
+// MemoryCache 进程内缓存，行为与 RedisClient 一致，适用于单元测试和单实例部署。
+// 过期的key在访问时删除，并在写入时定期清理
+type MemoryCache struct {
+	mu     sync.Mutex
+	items  map[string]memoryItem
+	writes int
+	now    func() time.Time
+}
+
+// NewMemoryCache 创建内存缓存
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		items: make(map[string]memoryItem),
+		now:   time.Now,
+	}
+}
+
+// GetContext 获取缓存，不存在时返回 ErrCacheMiss
+func (c *MemoryCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.get(key)
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	// 返回副本，避免调用方修改缓存内容
+	return append([]byte(nil), item.value...), nil
+}
+
+// SetContext 设置缓存，value支持的类型与 go-redis 一致，expiration为0表示不过期
+func (c *MemoryCache) SetContext(ctx context.Context, key string, value any, expiration time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	raw, err := toBytes(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, memoryItem{value: raw, expireAt: c.expireAt(expiration)})
+	return nil
+}
+
+// ExistsContext 判断key是否存在
+func (c *MemoryCache) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.get(key)
+	return ok, nil
+}
+
+// DeleteContext 删除key，返回key是否存在
+func (c *MemoryCache) DeleteContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.get(key)
+	delete(c.items, key)
+	return ok, nil
+}
+
+// TTLContext 返回剩余过期时间，不过期返回 NoExpiration，不存在返回 ErrCacheMiss
+func (c *MemoryCache) TTLContext(ctx context.Context, key string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.get(key)
+	if !ok {
+		return 0, ErrCacheMiss
+	}
+	if item.expireAt.IsZero() {
+		return NoExpiration, nil
+	}
+	return item.expireAt.Sub(c.now()), nil
+}
+
+// ExpireContext 设置过期时间，返回key是否存在，expiration小于等于0时删除key
+func (c *MemoryCache) ExpireContext(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.get(key)
+	if !ok {
+		return false, nil
+	}
+	if expiration <= 0 {
+		delete(c.items, key)
+		return true, nil
+	}
+	item.expireAt = c.now().Add(expiration)
+	c.items[key] = item
+	return true, nil
+}
+
+// IncrByContext 整数自增，key不存在时从0开始，保留原有过期时间
+func (c *MemoryCache) IncrByContext(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.get(key)
+	var n int64
+	if ok {
+		var err error
+		if n, err = strconv.ParseInt(string(item.value), 10, 64); err != nil {
+			return 0, errors.New("value is not an integer or out of range")
+		}
+	}
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return 0, errors.New("increment or decrement would overflow")
+	}
+	n += delta
+	item.value = strconv.AppendInt(nil, n, 10)
+	c.set(key, item)
+	return n, nil
+}
+
+// TryLock 非阻塞加锁，锁已被占用时返回 ErrLockNotAcquired
+func (c *MemoryCache) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		return nil, errInvalidLockTTL
+	}
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.get(key); ok {
+		return nil, ErrLockNotAcquired
+	}
+	c.set(key, memoryItem{value: []byte(token), expireAt: c.expireAt(ttl)})
+	return &memoryLock{cache: c, key: key, token: token}, nil
+}
+
+// Flush 清空缓存
+func (c *MemoryCache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]memoryItem)
+}
+
+// get 获取未过期的条目，过期的条目顺便删除，调用方需持有锁
+func (c *MemoryCache) get(key string) (memoryItem, bool) {
+	item, ok := c.items[key]
+	if !ok {
+		return item, false
+	}
+	if !item.expireAt.IsZero() && !c.now().Before(item.expireAt) {
+		delete(c.items, key)
+		return memoryItem{}, false
+	}
+	return item, true
+}
+
+// set 写入条目并定期清理过期key，调用方需持有锁
+func (c *MemoryCache) set(key string, item memoryItem) {
+	c.items[key] = item
+	c.writes++
+	if c.writes%memorySweepInterval != 0 {
+		return
+	}
+	now := c.now()
+	for k, v := range c.items {
+		if !v.expireAt.IsZero() && !now.Before(v.expireAt) {
+			delete(c.items, k)
+		}
+	}
+}
+
+func (c *MemoryCache) expireAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return c.now().Add(expiration)
+}
+
+// memoryLock 内存缓存的锁，与 redisLock 一样以随机标识区分持有者
+type memoryLock struct {
+	cache *MemoryCache
+	key   string
+	token string
+}
+
+func (l *memoryLock) Key() string {
+	return l.key
+}
+
+func (l *memoryLock) Unlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.cache.mu.Lock()
+	defer l.cache.mu.Unlock()
+	if !l.held() {
+		return ErrLockNotHeld
+	}
+	delete(l.cache.items, l.key)
+	return nil
+}
+
+func (l *memoryLock) Extend(ctx context.Context, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return errInvalidLockTTL
+	}
+	l.cache.mu.Lock()
+	defer l.cache.mu.Unlock()
+	if !l.held() {
+		return ErrLockNotHeld
+	}
+	l.cache.items[l.key] = memoryItem{value: []byte(l.token), expireAt: l.cache.expireAt(ttl)}
+	return nil
+}
+
+// held 调用方需持有缓存的锁
+func (l *memoryLock) held() bool {
+	item, ok := l.cache.get(l.key)
+	return ok && string(item.value) == l.token
+}
+
+// toBytes 按 go-redis 的规则把缓存值转换为字节
+func toBytes(v any) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return []byte{}, nil
+	case string:
+		return []byte(val), nil
+	case []byte:
+		return append([]byte(nil), val...), nil
+	case int:
+		return strconv.AppendInt(nil, int64(val), 10), nil
+	case int8:
+		return strconv.AppendInt(nil, int64(val), 10), nil
+	case int16:
+		return strconv.AppendInt(nil, int64(val), 10), nil
+	case int32:
+		return strconv.AppendInt(nil, int64(val), 10), nil
+	case int64:
+		return strconv.AppendInt(nil, val, 10), nil
+	case uint:
+		return strconv.AppendUint(nil, uint64(val), 10), nil
+	case uint8:
+		return strconv.AppendUint(nil, uint64(val), 10), nil
+	case uint16:
+		return strconv.AppendUint(nil, uint64(val), 10), nil
+	case uint32:
+		return strconv.AppendUint(nil, uint64(val), 10), nil
+	case uint64:
+		return strconv.AppendUint(nil, val, 10), nil
+	case float32:
+		return strconv.AppendFloat(nil, float64(val), 'f', -1, 32), nil
+	case float64:
+		return strconv.AppendFloat(nil, val, 'f', -1, 64), nil
+	case bool:
+		if val {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case time.Time:
+		return val.AppendFormat(nil, time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.AppendInt(nil, val.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		return val.MarshalBinary()
+	default:
+		return nil, fmt.Errorf("can't marshal %T (implement encoding.BinaryMarshaler)", v)
+	}
+}
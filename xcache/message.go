@@ -0,0 +1,50 @@
+package xcache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RichXan/xcommon/xutil"
+)
+
+// Message Redis Streams 或 pub/sub 的消息，字段与Pulsar消息对应
+type Message struct {
+	ID         string            // 消息ID，pub/sub消息为空
+	Topic      string            // stream名或频道名
+	Payload    []byte            // 消息内容
+	Properties map[string]string // 消息属性，pub/sub消息为空
+	Deliveries int64             // 投递次数，从1开始，pub/sub消息为1
+}
+
+// MessageHandler 消息处理函数，返回nil表示确认消息；
+// Streams消息返回错误后保留在待确认列表中，超过空闲时间后重新投递
+type MessageHandler func(ctx context.Context, msg *Message) error
+
+// TypedHandler 用codec把消息内容解码为T后调用fn
+func TypedHandler[T any](codec Codec, fn func(ctx context.Context, v T) error) MessageHandler {
+	return func(ctx context.Context, msg *Message) error {
+		var v T
+		if err := codec.Unmarshal(msg.Payload, &v); err != nil {
+			return fmt.Errorf("decode message %s failed: %w", msg.ID, err)
+		}
+		return fn(ctx, v)
+	}
+}
+
+// JSONHandler 把JSON格式的消息内容解码为T后调用fn
+func JSONHandler[T any](fn func(ctx context.Context, v T) error) MessageHandler {
+	return TypedHandler(JSONCodec, fn)
+}
+
+// handleMessage 调用handler，panic转为错误，消息属性中的请求ID保存到context
+func handleMessage(ctx context.Context, handler MessageHandler, msg *Message) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handle message %s panic: %v", msg.ID, p)
+		}
+	}()
+	if requestID := msg.Properties[xutil.RequestIDProperty]; requestID != "" {
+		ctx = xutil.WithRequestID(ctx, requestID)
+	}
+	return handler(ctx, msg)
+}
@@ -0,0 +1,116 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PublishContext 发布消息，返回收到消息的订阅者数量
+func (r *RedisClient) PublishContext(ctx context.Context, channel string, payload []byte) (int64, error) {
+	st := time.Now()
+	n, err := r.rdb.Publish(ctx, channel, payload).Result()
+	r.logger.Info().Str("channel", channel).Int64("receivers", n).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("publish finish")
+	return n, err
+}
+
+// PublishAs 用codec编码后发布消息
+func PublishAs(ctx context.Context, r *RedisClient, codec Codec, channel string, v any) (int64, error) {
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return r.PublishContext(ctx, channel, payload)
+}
+
+// PublishJSON 以JSON格式发布消息
+func PublishJSON(ctx context.Context, r *RedisClient, channel string, v any) (int64, error) {
+	return PublishAs(ctx, r, JSONCodec, channel, v)
+}
+
+// Subscriber pub/sub订阅者，连接断开后按指数退避自动重新订阅。
+// pub/sub不保证送达，断开期间的消息会丢失，需要可靠投递时使用 StreamConsumer
+type Subscriber struct {
+	client     *RedisClient
+	channels   []string
+	handler    MessageHandler
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	subscribed chan struct{}
+}
+
+// NewSubscriber 创建订阅者，调用 Run 开始订阅
+func NewSubscriber(r *RedisClient, handler MessageHandler, channels ...string) *Subscriber {
+	if handler == nil {
+		panic("message handler is nil")
+	}
+	if len(channels) == 0 {
+		panic("channels are empty")
+	}
+	return &Subscriber{
+		client:     r,
+		channels:   channels,
+		handler:    handler,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		subscribed: make(chan struct{}, 1),
+	}
+}
+
+// Subscribed 每次订阅成功后收到通知，用于等待订阅生效后再发布消息
+func (s *Subscriber) Subscribed() <-chan struct{} {
+	return s.subscribed
+}
+
+// Run 订阅并处理消息直到ctx结束，handler按消息顺序串行调用
+func (s *Subscriber) Run(ctx context.Context) error {
+	backoff := s.minBackoff
+	for {
+		err := s.receive(ctx, func() { backoff = s.minBackoff })
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.client.logger.Error().Strs("channels", s.channels).Err(err).Dur("retry", backoff).Msg("subscription interrupted")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.maxBackoff)
+	}
+}
+
+// receive 订阅一次，连接出错时返回
+func (s *Subscriber) receive(ctx context.Context, onSubscribed func()) error {
+	pubsub := s.client.rdb.Subscribe(ctx, s.channels...)
+	defer pubsub.Close()
+	// Receive 不会因ctx取消而返回，关闭连接使其退出
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer stop()
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			return err
+		}
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" && m.Count == len(s.channels) {
+				onSubscribed()
+				select {
+				case s.subscribed <- struct{}{}:
+				default:
+				}
+			}
+		case *redis.Message:
+			message := &Message{Topic: m.Channel, Payload: []byte(m.Payload), Deliveries: 1}
+			if err := handleMessage(ctx, s.handler, message); err != nil {
+				s.client.logger.Error().Str("channel", m.Channel).Err(err).Msg("handle message failed")
+			}
+		case *redis.Pong:
+		default:
+			return errors.New("unexpected pubsub message")
+		}
+	}
+}
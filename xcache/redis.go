@@ -33,7 +33,7 @@ type RedisClient struct {
 	rdb     redis.UniversalClient
 	logger  xlog.Logger
 	redSync *redsync.Redsync // 分布式锁对象
-	mutex   sync.Mutex
+	once    sync.Once        // 保证redSync只初始化一次
 }
 
 func NewRedisClient(masterName string, addresses []string, password string, logger *xlog.Logger) (*RedisClient, error) {
@@ -124,89 +124,199 @@ func (r *RedisClient) Client() redis.UniversalClient {
 	return r.rdb
 }
 
-// 创建分布式锁对象
+// 创建分布式锁对象，并发调用时只初始化一次
 func (r *RedisClient) newRedisSync(redisClient redis.UniversalClient) *redsync.Redsync {
-	if r.redSync == nil {
-		r.mutex.Lock()
-		defer r.mutex.Unlock()
-		if redisClient == nil {
-			panic("redis client is nil")
-		}
-		r.redSync = redsync.New(goredis.NewPool(redisClient))
+	if redisClient == nil {
+		panic("redis client is nil")
 	}
+	r.once.Do(func() {
+		r.redSync = redsync.New(goredis.NewPool(redisClient))
+	})
 	return r.redSync
 }
 
+// NewMutex 创建分布式锁，调用方负责加锁和释放
+func (r *RedisClient) NewMutex(key string, options ...redsync.Option) *redsync.Mutex {
+	return r.newRedisSync(r.rdb).NewMutex(key, options...)
+}
+
 // 创建一个影子redis对象，用于分布式锁
 func (r *RedisClient) Shadow(logger *xlog.Logger) *RedisClient {
 	if r.rdb == nil {
 		panic("redis client is nil")
 	}
-	instance := &RedisClient{r.rdb, *logger, nil, sync.Mutex{}}
+	instance := &RedisClient{rdb: r.rdb, logger: *logger}
 	instance.newRedisSync(r.rdb)
 	return instance
 }
 
-func (r *RedisClient) Set(k, v string, expiration, timeout time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// ErrCacheMiss 缓存不存在，Context结尾的方法和泛型方法用它代替 redis.Nil
+var ErrCacheMiss = errors.New("cache miss")
+
+// SetContext 设置缓存，v支持string、[]byte和数字等 go-redis 支持的类型，expiration为0表示不过期
+func (r *RedisClient) SetContext(ctx context.Context, k string, v any, expiration time.Duration) error {
 	st := time.Now()
 	err := r.rdb.Set(ctx, k, v, expiration).Err()
-	r.logger.Info().Str("key", k).Str("value", v).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("set redis finish")
+	r.logger.Info().Str("key", k).Str("value", logValue(v)).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("set redis finish")
 	return err
 }
 
+// GetContext 获取缓存，不存在时返回 ErrCacheMiss
+func (r *RedisClient) GetContext(ctx context.Context, k string) ([]byte, error) {
+	st := time.Now()
+	v, e := r.rdb.Get(ctx, k).Bytes()
+	r.logger.Info().Str("key", k).Any("value", string(v)).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("get redis finish")
+	if errors.Is(e, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return v, e
+}
+
+// getWithTTL 在一次往返中获取缓存和剩余过期时间，不过期时返回 NoExpiration
+func (r *RedisClient) getWithTTL(ctx context.Context, k string) ([]byte, time.Duration, error) {
+	st := time.Now()
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, e := r.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, k)
+		pttl = pipe.PTTL(ctx, k)
+		return nil
+	})
+	v, _ := get.Bytes()
+	ttl := pttl.Val()
+	r.logger.Info().Str("key", k).Any("value", string(v)).Dur("ttl", ttl).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("get redis finish")
+	if errors.Is(e, redis.Nil) {
+		return nil, 0, ErrCacheMiss
+	}
+	if e != nil {
+		return nil, 0, e
+	}
+	switch ttl {
+	case -2:
+		// GET和PTTL之间key已过期
+		return nil, 0, ErrCacheMiss
+	case -1:
+		ttl = NoExpiration
+	}
+	return v, ttl, nil
+}
+
+// ExistsContext 判断key是否存在
+func (r *RedisClient) ExistsContext(ctx context.Context, k string) (bool, error) {
+	st := time.Now()
+	v, e := r.rdb.Exists(ctx, k).Result()
+	r.logger.Info().Str("key", k).Int64("Exists", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("key exists finish")
+	return v > 0, e
+}
+
+// DeleteContext 删除key，返回key是否存在
+func (r *RedisClient) DeleteContext(ctx context.Context, k string) (bool, error) {
+	st := time.Now()
+	v, e := r.rdb.Del(ctx, k).Result()
+	r.logger.Info().Str("key", k).Int64("Deleted", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("key delete finish")
+	return v > 0, e
+}
+
+// TTLContext 返回剩余过期时间，不过期返回 NoExpiration，不存在返回 ErrCacheMiss
+func (r *RedisClient) TTLContext(ctx context.Context, k string) (time.Duration, error) {
+	st := time.Now()
+	v, e := r.rdb.PTTL(ctx, k).Result()
+	r.logger.Info().Str("key", k).Dur("ttl", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("key ttl finish")
+	if e != nil {
+		return 0, e
+	}
+	// go-redis 对不存在和不过期的key分别返回-2和-1
+	switch v {
+	case -2:
+		return 0, ErrCacheMiss
+	case -1:
+		return NoExpiration, nil
+	}
+	return v, nil
+}
+
+// ExpireContext 设置过期时间，返回key是否存在，expiration小于等于0时删除key
+func (r *RedisClient) ExpireContext(ctx context.Context, k string, expiration time.Duration) (bool, error) {
+	if expiration <= 0 {
+		return r.DeleteContext(ctx, k)
+	}
+	st := time.Now()
+	v, e := r.rdb.PExpire(ctx, k, expiration).Result()
+	r.logger.Info().Str("key", k).Dur("expiration", expiration).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("key expire finish")
+	return v, e
+}
+
+// IncrByContext 整数自增，key不存在时从0开始
+func (r *RedisClient) IncrByContext(ctx context.Context, k string, delta int64) (int64, error) {
+	st := time.Now()
+	v, e := r.rdb.IncrBy(ctx, k, delta).Result()
+	r.logger.Info().Str("key", k).Int64("value", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("incr redis finish")
+	return v, e
+}
+
+// Set 设置缓存，推荐使用 SetContext 传递调用方的context
+func (r *RedisClient) Set(k, v string, expiration, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return r.SetContext(ctx, k, v, expiration)
+}
+
+// Get 获取缓存，不存在时返回 redis.Nil，推荐使用 GetContext
 func (r *RedisClient) Get(k string, timeout time.Duration) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	st := time.Now()
-	v, e := r.rdb.Get(ctx, k).Bytes()
-	vs := ""
-	if v != nil {
-		vs = string(v)
+	v, e := r.GetContext(ctx, k)
+	if errors.Is(e, ErrCacheMiss) {
+		return nil, redis.Nil
 	}
-	r.logger.Info().Str("key", k).Any("value", vs).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("get redis finish")
 	return v, e
 }
 
+// Exists 判断key是否存在，推荐使用 ExistsContext
 func (r *RedisClient) Exists(k string, timeout time.Duration) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	st := time.Now()
-	v, e := r.rdb.Exists(ctx, k).Result()
-	r.logger.Info().Str("key", k).Int64("Exists", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("key exists finish")
-	return v > 0
+	v, _ := r.ExistsContext(ctx, k)
+	return v
 }
 
+// Delete 删除key，推荐使用 DeleteContext
 func (r *RedisClient) Delete(k string, timeout time.Duration) (bool, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	st := time.Now()
-	v, e := r.rdb.Del(ctx, k).Result()
-	r.logger.Info().Str("key", k).Int64("Deleted", v).Any("error", e).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("key exists finish")
-	return v > 0, e
+	return r.DeleteContext(ctx, k)
 }
 
-// 竞争的key，成功后执行fn，fn执行完毕后释放锁
+// logValue 日志中记录的缓存值
+func logValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// RedLockFunc 竞争key的锁，成功后执行fn，fn返回或panic后释放锁，panic会继续向上抛出。
+// 未获取到锁时返回 ErrLockNotAcquired；需要自动续期、可重入或按context等待时使用 WithLock
 func (r *RedisClient) RedLockFunc(key string, fn func() error, options ...redsync.Option) (err error) {
 	s := time.Now().UnixMilli()
-	redSyncMutex := r.redSync.NewMutex(key, options...)
+	redSyncMutex := r.newRedisSync(r.rdb).NewMutex(key, options...)
 	if err = redSyncMutex.Lock(); err != nil {
 		r.logger.Error().Msgf("redlock %s error %v", key, err)
-		return errors.New("busy business, please try again later")
+		return fmt.Errorf("%w: %s: %v", ErrLockNotAcquired, key, err)
 	}
 	defer func() {
-		if recoverErr := recover(); recoverErr != nil {
-			r.logger.Error().Msgf("redlock %s error %v", key, recoverErr)
-		}
-		exists := r.Exists(key, time.Second*30)
-		if exists { //存在key才释放，防止出现超时key失效异常
-			if ok, err1 := redSyncMutex.Unlock(); !ok || err1 != nil {
-				r.logger.Error().Msgf("redlock %s unlock error %v", key, err1)
+		// redsync 只释放自己持有的锁，锁已过期时返回 ErrLockAlreadyExpired
+		if ok, err1 := redSyncMutex.Unlock(); !ok || err1 != nil {
+			r.logger.Error().Msgf("redlock %s unlock error %v", key, err1)
+			if err == nil {
 				err = err1
-			} else {
-				r.logger.Info().Msgf("redlock %s unlock cost %d", key, time.Now().UnixMilli()-s)
 			}
+		} else {
+			r.logger.Info().Msgf("redlock %s unlock cost %d", key, time.Now().UnixMilli()-s)
 		}
 	}()
 	err = fn()
@@ -0,0 +1,82 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisClient(t *testing.T) (*RedisClient, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client, err := NewRedisClientByConfig(&RedisConfig{
+		Addresses: []string{mr.Addr()},
+	}, xlog.NewLogger(xlog.LoggerConfig{Level: "error"}))
+	require.NoError(t, err)
+	return client, mr
+}
+
+func TestRedisClientContext(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	_, err := client.GetContext(ctx, "missing")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	// 旧方法保持返回 redis.Nil
+	_, err = client.Get("missing", time.Second)
+	assert.ErrorIs(t, err, redis.Nil)
+
+	require.NoError(t, client.SetContext(ctx, "k", []byte("v"), time.Minute))
+	v, err := client.GetContext(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, "v", string(v))
+	assert.Equal(t, time.Minute, mr.TTL("k"))
+
+	exists, err := client.ExistsContext(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	deleted, err := client.DeleteContext(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	// 调用方取消的context会传递给Redis命令
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.True(t, errors.Is(client.SetContext(canceled, "k", "v", 0), context.Canceled))
+}
+
+func TestTypedCache(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	type user struct {
+		ID   int
+		Name string
+		Tags []string
+	}
+	want := user{ID: 1, Name: "alice", Tags: []string{"admin"}}
+
+	for name, codec := range map[string]Codec{"json": JSONCodec, "msgpack": MsgpackCodec, "gob": GobCodec} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, SetAs(ctx, client, codec, "user:"+name, want, time.Minute))
+			got, err := GetAs[user](ctx, client, codec, "user:"+name)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+
+	require.NoError(t, SetJSON(ctx, client, "user", want, 0))
+	got, err := GetJSON[user](ctx, client, "user")
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	_, err = GetJSON[user](ctx, client, "nobody")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
@@ -0,0 +1,161 @@
+package xcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// luaNowMillis 使用Redis服务端时间，避免各实例时钟不一致时误清理其他实例的租约
+const luaNowMillis = `
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+`
+
+// 信号量保存为有序集合，member为持有者，score为租约到期的毫秒时间戳
+var (
+	// 清理过期的持有者后，未满时加入，返回1表示获取成功
+	semaphoreAcquireScript = redis.NewScript(luaNowMillis + `
+local lease = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= limit then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + lease, ARGV[3])
+if redis.call('PTTL', KEYS[1]) < lease then
+	redis.call('PEXPIRE', KEYS[1], lease)
+end
+return 1
+`)
+	// 续租未过期的持有者，返回1表示续租成功
+	semaphoreExtendScript = redis.NewScript(luaNowMillis + `
+local lease = tonumber(ARGV[1])
+local score = redis.call('ZSCORE', KEYS[1], ARGV[2])
+if not score or tonumber(score) <= now then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + lease, ARGV[2])
+if redis.call('PTTL', KEYS[1]) < lease then
+	redis.call('PEXPIRE', KEYS[1], lease)
+end
+return 1
+`)
+	// 释放时未过期返回1
+	semaphoreReleaseScript = redis.NewScript(luaNowMillis + `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[1], ARGV[1])
+if not score or tonumber(score) <= now then
+	return 0
+end
+return 1
+`)
+	// 未过期的持有者数量
+	semaphoreCountScript = redis.NewScript(luaNowMillis + `
+return redis.call('ZCOUNT', KEYS[1], '(' .. now, '+inf')
+`)
+)
+
+// Semaphore 分布式信号量，最多limit个持有者同时持有，持有者未续租时租约到期自动释放
+type Semaphore struct {
+	client *RedisClient
+	key    string
+	limit  int
+	lease  time.Duration
+}
+
+// NewSemaphore 创建信号量，lease为租约时间
+func NewSemaphore(r *RedisClient, key string, limit int, lease time.Duration) *Semaphore {
+	if limit <= 0 {
+		panic("semaphore limit must be positive")
+	}
+	if lease <= 0 {
+		panic("semaphore lease must be positive")
+	}
+	return &Semaphore{client: r, key: key, limit: limit, lease: lease}
+}
+
+// TryAcquire 非阻塞获取，已满时返回 ErrLockNotAcquired。返回的 Lock 用于释放和续租
+func (s *Semaphore) TryAcquire(ctx context.Context) (Lock, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+	n, err := semaphoreAcquireScript.Run(ctx, s.client.rdb, []string{s.key}, s.lease.Milliseconds(), s.limit, token).Int64()
+	if err != nil {
+		s.client.logger.Error().Str("key", s.key).Err(err).Msg("acquire semaphore failed")
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("%w: semaphore %s is full", ErrLockNotAcquired, s.key)
+	}
+	return &semaphorePermit{semaphore: s, token: token}, nil
+}
+
+// Acquire 获取，已满时每隔interval重试，直到获取成功或ctx结束
+func (s *Semaphore) Acquire(ctx context.Context, interval time.Duration) (Lock, error) {
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	for {
+		permit, err := s.TryAcquire(ctx)
+		if err == nil {
+			return permit, nil
+		}
+		if !errors.Is(err, ErrLockNotAcquired) && ctx.Err() == nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: semaphore %s: %v", ErrLockNotAcquired, s.key, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Count 返回当前未过期的持有者数量
+func (s *Semaphore) Count(ctx context.Context) (int64, error) {
+	return semaphoreCountScript.Run(ctx, s.client.rdb, []string{s.key}).Int64()
+}
+
+// semaphorePermit 信号量的一个许可
+type semaphorePermit struct {
+	semaphore *Semaphore
+	token     string
+}
+
+func (p *semaphorePermit) Key() string {
+	return p.semaphore.key
+}
+
+// Unlock 释放许可，租约已过期时返回 ErrLockNotHeld
+func (p *semaphorePermit) Unlock(ctx context.Context) error {
+	s := p.semaphore
+	n, err := semaphoreReleaseScript.Run(ctx, s.client.rdb, []string{s.key}, p.token).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Extend 将租约续期为ttl，租约已过期时返回 ErrLockNotHeld
+func (p *semaphorePermit) Extend(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errInvalidLockTTL
+	}
+	s := p.semaphore
+	n, err := semaphoreExtendScript.Run(ctx, s.client.rdb, []string{s.key}, ttl.Milliseconds(), p.token).Int64()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package xcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+	sem := NewSemaphore(client, "sem", 2, 200*time.Millisecond)
+
+	p1, err := sem.TryAcquire(ctx)
+	require.NoError(t, err)
+	p2, err := sem.TryAcquire(ctx)
+	require.NoError(t, err)
+	_, err = sem.TryAcquire(ctx)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+	count, err := sem.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	require.NoError(t, p1.Unlock(ctx))
+	p3, err := sem.TryAcquire(ctx)
+	require.NoError(t, err)
+
+	// p2续租，p3租约到期后自动释放
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, p2.Extend(ctx, time.Second))
+	time.Sleep(150 * time.Millisecond)
+	assert.ErrorIs(t, p3.Extend(ctx, time.Second), ErrLockNotHeld)
+	assert.ErrorIs(t, p3.Unlock(ctx), ErrLockNotHeld)
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	p4, err := sem.Acquire(waitCtx, 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = sem.TryAcquire(ctx)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+
+	require.NoError(t, p2.Unlock(ctx))
+	require.NoError(t, p4.Unlock(ctx))
+}
+
+func TestSemaphoreServerTime(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+	sem := NewSemaphore(client, "sem", 1, time.Minute)
+
+	// 租约按Redis服务端时间计算，客户端时钟快于服务端时不会清理未过期的持有者
+	serverNow := time.Now().Add(-time.Hour)
+	mr.SetTime(serverNow)
+	_, err := mr.ZAdd("sem", float64(serverNow.Add(time.Minute).UnixMilli()), "other")
+	require.NoError(t, err)
+	_, err = sem.TryAcquire(ctx)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+	count, err := sem.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	mr.SetTime(serverNow.Add(time.Minute + time.Millisecond))
+	p, err := sem.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.NoError(t, p.Unlock(ctx))
+}
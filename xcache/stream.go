@@ -0,0 +1,343 @@
+package xcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichXan/xcommon/xutil"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// stream消息的字段
+const (
+	streamPayloadField    = "payload"
+	streamPropertiesField = "properties"
+)
+
+// 死信消息中记录来源的属性
+const (
+	DeadLetterStreamProperty     = "dead_letter_stream"
+	DeadLetterIDProperty         = "dead_letter_id"
+	DeadLetterDeliveriesProperty = "dead_letter_deliveries"
+)
+
+// StreamProducer Redis Streams 生产者
+type StreamProducer struct {
+	client *RedisClient
+	stream string
+	maxLen int64
+}
+
+// NewStreamProducer 创建生产者，maxLen大于0时近似裁剪stream长度
+func NewStreamProducer(r *RedisClient, stream string, maxLen int64) *StreamProducer {
+	return &StreamProducer{client: r, stream: stream, maxLen: maxLen}
+}
+
+// Send 发送消息并返回消息ID，context中的请求ID写入消息属性
+func (p *StreamProducer) Send(ctx context.Context, payload []byte, properties map[string]string) (string, error) {
+	if requestID := xutil.RequestIDFromContext(ctx); requestID != "" {
+		if _, ok := properties[xutil.RequestIDProperty]; !ok {
+			props := make(map[string]string, len(properties)+1)
+			for k, v := range properties {
+				props[k] = v
+			}
+			props[xutil.RequestIDProperty] = requestID
+			properties = props
+		}
+	}
+	values := map[string]any{streamPayloadField: payload}
+	if len(properties) > 0 {
+		raw, err := json.Marshal(properties)
+		if err != nil {
+			return "", err
+		}
+		values[streamPropertiesField] = raw
+	}
+	args := &redis.XAddArgs{Stream: p.stream, Values: values}
+	if p.maxLen > 0 {
+		args.MaxLen = p.maxLen
+		args.Approx = true
+	}
+	st := time.Now()
+	id, err := p.client.rdb.XAdd(ctx, args).Result()
+	p.client.logger.Info().Str("stream", p.stream).Str("id", id).Any("error", err).Int("cost(ms)", int(time.Since(st).Milliseconds())).Msg("stream send finish")
+	return id, err
+}
+
+// SendAs 用codec编码后发送
+func (p *StreamProducer) SendAs(ctx context.Context, codec Codec, v any) (string, error) {
+	payload, err := codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return p.Send(ctx, payload, nil)
+}
+
+// SendJSON 以JSON格式发送
+func (p *StreamProducer) SendJSON(ctx context.Context, v any) (string, error) {
+	return p.SendAs(ctx, JSONCodec, v)
+}
+
+// StreamConsumerOptions 消费者组配置
+type StreamConsumerOptions struct {
+	// stream名，必填
+	Stream string
+	// 消费者组名，必填
+	Group string
+	// 消息处理函数，必填
+	Handler MessageHandler
+	// 消费者名，同一组内唯一，默认主机名加随机后缀
+	Consumer string
+	// 创建消费者组时的起始ID，默认 $ 只消费之后的消息，0 表示从头消费
+	StartID string
+	// 每次读取的消息数，默认10
+	Count int64
+	// 没有消息时阻塞等待的时间，也是 Run 退出的最长延迟，默认5秒
+	Block time.Duration
+	// 待确认消息空闲超过该时间后被重新认领，默认30秒
+	MinIdle time.Duration
+	// 认领空闲消息的间隔，默认 MinIdle/2
+	ClaimInterval time.Duration
+	// 最大投递次数，超过后转入死信stream，默认5，小于0表示不限制
+	MaxDeliveries int64
+	// 死信stream，默认 Stream + ":dlq"
+	DeadLetterStream string
+}
+
+// StreamConsumer Redis Streams 消费者组的消费者。
+// 处理失败的消息保留在待确认列表中，空闲超过 MinIdle 后由组内任一消费者通过 XAUTOCLAIM 认领重试，
+// 投递次数超过 MaxDeliveries 后转入死信stream
+type StreamConsumer struct {
+	client *RedisClient
+	opts   StreamConsumerOptions
+}
+
+// NewStreamConsumer 创建消费者，消费者组不存在时自动创建
+func NewStreamConsumer(ctx context.Context, r *RedisClient, opts StreamConsumerOptions) (*StreamConsumer, error) {
+	if opts.Stream == "" || opts.Group == "" {
+		return nil, errors.New("stream and group are required")
+	}
+	if opts.Handler == nil {
+		return nil, errors.New("message handler is nil")
+	}
+	if opts.Consumer == "" {
+		token, err := newLockToken()
+		if err != nil {
+			return nil, err
+		}
+		hostname, _ := os.Hostname()
+		opts.Consumer = hostname + "-" + token[:8]
+	}
+	if opts.StartID == "" {
+		opts.StartID = "$"
+	}
+	if opts.Count <= 0 {
+		opts.Count = 10
+	}
+	if opts.Block <= 0 {
+		opts.Block = 5 * time.Second
+	}
+	if opts.MinIdle <= 0 {
+		opts.MinIdle = 30 * time.Second
+	}
+	if opts.ClaimInterval <= 0 {
+		opts.ClaimInterval = opts.MinIdle / 2
+	}
+	if opts.MaxDeliveries == 0 {
+		opts.MaxDeliveries = 5
+	}
+	if opts.DeadLetterStream == "" {
+		opts.DeadLetterStream = opts.Stream + ":dlq"
+	}
+	c := &StreamConsumer{client: r, opts: opts}
+	if err := c.createGroup(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run 消费消息直到ctx结束
+func (c *StreamConsumer) Run(ctx context.Context) error {
+	var lastClaim time.Time
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(lastClaim) >= c.opts.ClaimInterval {
+			c.reclaim(ctx)
+			lastClaim = time.Now()
+		}
+
+		streams, err := c.client.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			Streams:  []string{c.opts.Stream, ">"},
+			Count:    c.opts.Count,
+			Block:    c.opts.Block,
+		}).Result()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.client.logger.Error().Str("stream", c.opts.Stream).Str("group", c.opts.Group).Err(err).Msg("read stream failed")
+			// stream或消费者组被删除后重新创建
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				_ = c.createGroup(ctx)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				c.process(ctx, msg, 1)
+			}
+		}
+	}
+}
+
+func (c *StreamConsumer) createGroup(ctx context.Context) error {
+	err := c.client.rdb.XGroupCreateMkStream(ctx, c.opts.Stream, c.opts.Group, c.opts.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// reclaim 认领空闲超时的待确认消息并处理
+func (c *StreamConsumer) reclaim(ctx context.Context) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		msgs, next, err := c.client.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   c.opts.Stream,
+			Group:    c.opts.Group,
+			Consumer: c.opts.Consumer,
+			MinIdle:  c.opts.MinIdle,
+			Start:    start,
+			Count:    c.opts.Count,
+		}).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				c.client.logger.Error().Str("stream", c.opts.Stream).Str("group", c.opts.Group).Err(err).Msg("claim pending messages failed")
+			}
+			return
+		}
+		if len(msgs) > 0 {
+			deliveries := c.deliveries(ctx, msgs)
+			for _, msg := range msgs {
+				// 投递次数未知时无法判断是否转入死信，保留为待确认，下次认领时再处理
+				n, ok := deliveries[msg.ID]
+				if !ok {
+					continue
+				}
+				c.process(ctx, msg, n)
+			}
+		}
+		if next == "0-0" || next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+// deliveries 查询消息的投递次数，查询失败的消息不在结果中
+func (c *StreamConsumer) deliveries(ctx context.Context, msgs []redis.XMessage) map[string]int64 {
+	cmds := make([]*redis.XPendingExtCmd, len(msgs))
+	_, _ = c.client.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, msg := range msgs {
+			cmds[i] = pipe.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: c.opts.Stream,
+				Group:  c.opts.Group,
+				Start:  msg.ID,
+				End:    msg.ID,
+				Count:  1,
+			})
+		}
+		return nil
+	})
+	result := make(map[string]int64, len(msgs))
+	for i, cmd := range cmds {
+		pending, err := cmd.Result()
+		switch {
+		case err != nil:
+			if ctx.Err() == nil {
+				c.client.logger.Error().Str("stream", c.opts.Stream).Str("id", msgs[i].ID).Err(err).Msg("get message deliveries failed")
+			}
+		case len(pending) > 0:
+			result[msgs[i].ID] = pending[0].RetryCount
+		}
+	}
+	return result
+}
+
+// process 处理消息，成功后确认，投递次数超限时转入死信stream
+func (c *StreamConsumer) process(ctx context.Context, xmsg redis.XMessage, deliveries int64) {
+	// 认领到已删除的消息时直接确认
+	if xmsg.Values == nil {
+		c.ack(ctx, xmsg.ID)
+		return
+	}
+	msg := c.toMessage(xmsg, deliveries)
+	if c.opts.MaxDeliveries > 0 && deliveries > c.opts.MaxDeliveries {
+		c.deadLetter(ctx, msg)
+		return
+	}
+	if err := handleMessage(ctx, c.opts.Handler, msg); err != nil {
+		c.client.logger.Error().Str("stream", c.opts.Stream).Str("id", msg.ID).Int64("deliveries", deliveries).Err(err).Msg("handle stream message failed")
+		return
+	}
+	c.ack(ctx, msg.ID)
+}
+
+func (c *StreamConsumer) ack(ctx context.Context, id string) {
+	if err := c.client.rdb.XAck(ctx, c.opts.Stream, c.opts.Group, id).Err(); err != nil {
+		c.client.logger.Error().Str("stream", c.opts.Stream).Str("id", id).Err(err).Msg("ack stream message failed")
+	}
+}
+
+// deadLetter 写入死信stream后确认原消息
+func (c *StreamConsumer) deadLetter(ctx context.Context, msg *Message) {
+	properties := make(map[string]string, len(msg.Properties)+3)
+	for k, v := range msg.Properties {
+		properties[k] = v
+	}
+	properties[DeadLetterStreamProperty] = c.opts.Stream
+	properties[DeadLetterIDProperty] = msg.ID
+	properties[DeadLetterDeliveriesProperty] = strconv.FormatInt(msg.Deliveries-1, 10)
+	raw, _ := json.Marshal(properties)
+	err := c.client.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.opts.DeadLetterStream,
+		Values: map[string]any{streamPayloadField: msg.Payload, streamPropertiesField: raw},
+	}).Err()
+	if err != nil {
+		c.client.logger.Error().Str("stream", c.opts.DeadLetterStream).Str("id", msg.ID).Err(err).Msg("send dead letter failed")
+		return
+	}
+	c.client.logger.Warn().Str("stream", c.opts.Stream).Str("id", msg.ID).Int64("deliveries", msg.Deliveries-1).Msg("message moved to dead letter stream")
+	c.ack(ctx, msg.ID)
+}
+
+func (c *StreamConsumer) toMessage(xmsg redis.XMessage, deliveries int64) *Message {
+	msg := &Message{ID: xmsg.ID, Topic: c.opts.Stream, Deliveries: deliveries}
+	if payload, ok := xmsg.Values[streamPayloadField].(string); ok {
+		msg.Payload = []byte(payload)
+	}
+	if raw, ok := xmsg.Values[streamPropertiesField].(string); ok && raw != "" {
+		if err := json.Unmarshal([]byte(raw), &msg.Properties); err != nil {
+			c.client.logger.Error().Str("stream", c.opts.Stream).Str("id", xmsg.ID).Err(err).Msg("decode message properties failed")
+		}
+	}
+	return msg
+}
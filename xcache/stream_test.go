@@ -0,0 +1,240 @@
+package xcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xutil"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderEvent struct {
+	OrderID int64  `json:"order_id"`
+	Status  string `json:"status"`
+}
+
+func runConsumer(t *testing.T, consumer *StreamConsumer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = consumer.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestStreamConsumer(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	received := make(chan orderEvent, 1)
+	var requestID atomic.Value
+	consumer, err := NewStreamConsumer(ctx, client, StreamConsumerOptions{
+		Stream: "orders",
+		Group:  "billing",
+		Block:  20 * time.Millisecond,
+		Handler: JSONHandler(func(ctx context.Context, e orderEvent) error {
+			requestID.Store(xutil.RequestIDFromContext(ctx))
+			received <- e
+			return nil
+		}),
+	})
+	require.NoError(t, err)
+	runConsumer(t, consumer)
+
+	producer := NewStreamProducer(client, "orders", 1000)
+	_, err = producer.SendJSON(xutil.WithRequestID(ctx, "req-1"), orderEvent{OrderID: 1, Status: "paid"})
+	require.NoError(t, err)
+
+	select {
+	case e := <-received:
+		assert.Equal(t, orderEvent{OrderID: 1, Status: "paid"}, e)
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+	assert.Equal(t, "req-1", requestID.Load())
+	require.Eventually(t, func() bool {
+		pending, err := client.Client().XPending(ctx, "orders", "billing").Result()
+		return err == nil && pending.Count == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamConsumerDeadLetter(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	consumer, err := NewStreamConsumer(ctx, client, StreamConsumerOptions{
+		Stream:        "orders",
+		Group:         "billing",
+		Block:         20 * time.Millisecond,
+		MinIdle:       50 * time.Millisecond,
+		ClaimInterval: 20 * time.Millisecond,
+		MaxDeliveries: 2,
+		Handler: func(ctx context.Context, msg *Message) error {
+			calls.Add(1)
+			if msg.Deliveries == 1 {
+				return errors.New("temporary failure")
+			}
+			panic("still failing")
+		},
+	})
+	require.NoError(t, err)
+	runConsumer(t, consumer)
+
+	id, err := NewStreamProducer(client, "orders", 0).Send(ctx, []byte("payload"), map[string]string{"tenant": "t1"})
+	require.NoError(t, err)
+
+	var dead []Message
+	require.Eventually(t, func() bool {
+		msgs, err := client.Client().XRange(ctx, "orders:dlq", "-", "+").Result()
+		if err != nil || len(msgs) == 0 {
+			return false
+		}
+		for _, m := range msgs {
+			dead = append(dead, *consumer.toMessage(m, 0))
+		}
+		return true
+	}, 2*time.Second, 20*time.Millisecond)
+
+	// 投递两次后转入死信stream，原消息已确认
+	assert.Equal(t, int32(2), calls.Load())
+	require.Len(t, dead, 1)
+	assert.Equal(t, "payload", string(dead[0].Payload))
+	assert.Equal(t, "t1", dead[0].Properties["tenant"])
+	assert.Equal(t, id, dead[0].Properties[DeadLetterIDProperty])
+	assert.Equal(t, "orders", dead[0].Properties[DeadLetterStreamProperty])
+	assert.Equal(t, "2", dead[0].Properties[DeadLetterDeliveriesProperty])
+	pending, err := client.Client().XPending(ctx, "orders", "billing").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+// failPendingHook 开启后pipeline中的XPENDING命令返回错误
+type failPendingHook struct {
+	fail atomic.Bool
+}
+
+func (h *failPendingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *failPendingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return next
+}
+
+func (h *failPendingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !h.fail.Load() {
+			return next(ctx, cmds)
+		}
+		err := errors.New("xpending failed")
+		for _, cmd := range cmds {
+			if cmd.Name() == "xpending" {
+				cmd.SetErr(err)
+			}
+		}
+		return err
+	}
+}
+
+func TestStreamConsumerDeliveriesUnknown(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	ctx := context.Background()
+	hook := &failPendingHook{}
+	client.Client().AddHook(hook)
+	hook.fail.Store(true)
+
+	var calls atomic.Int32
+	consumer, err := NewStreamConsumer(ctx, client, StreamConsumerOptions{
+		Stream:        "orders",
+		Group:         "billing",
+		Block:         20 * time.Millisecond,
+		MinIdle:       30 * time.Millisecond,
+		ClaimInterval: 20 * time.Millisecond,
+		MaxDeliveries: 2,
+		Handler: func(ctx context.Context, msg *Message) error {
+			calls.Add(1)
+			return errors.New("always failing")
+		},
+	})
+	require.NoError(t, err)
+	runConsumer(t, consumer)
+
+	_, err = NewStreamProducer(client, "orders", 0).Send(ctx, []byte("payload"), nil)
+	require.NoError(t, err)
+
+	// 查询投递次数失败时不处理认领的消息，避免无法转入死信而无限重试
+	time.Sleep(300 * time.Millisecond)
+	assert.Equal(t, int32(1), calls.Load())
+	pending, err := client.Client().XPending(ctx, "orders", "billing").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending.Count)
+
+	// 恢复后按投递次数转入死信stream
+	hook.fail.Store(false)
+	require.Eventually(t, func() bool {
+		n, err := client.Client().XLen(ctx, "orders:dlq").Result()
+		return err == nil && n == 1
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestSubscriber(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	received := make(chan orderEvent, 1)
+	sub := NewSubscriber(client, JSONHandler(func(ctx context.Context, e orderEvent) error {
+		received <- e
+		return nil
+	}), "events")
+	done := make(chan error)
+	go func() { done <- sub.Run(ctx) }()
+
+	publish := func(e orderEvent) {
+		select {
+		case <-sub.Subscribed():
+		case <-time.After(2 * time.Second):
+			t.Fatal("not subscribed")
+		}
+		_, err := PublishJSON(ctx, client, "events", e)
+		require.NoError(t, err)
+		select {
+		case got := <-received:
+			assert.Equal(t, e, got)
+		case <-time.After(time.Second):
+			t.Fatal("message not received")
+		}
+	}
+	publish(orderEvent{OrderID: 1})
+
+	// 连接断开后自动重新订阅
+	mr.Close()
+	require.NoError(t, mr.Restart())
+	publish(orderEvent{OrderID: 2})
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber not stopped")
+	}
+}
+
+func TestTypedHandler(t *testing.T) {
+	handler := JSONHandler(func(ctx context.Context, e orderEvent) error { return nil })
+	err := handler(context.Background(), &Message{ID: "1-0", Payload: []byte("not json")})
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+}
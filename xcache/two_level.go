@@ -0,0 +1,266 @@
+package xcache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TwoLevelOptions 二级缓存配置
+type TwoLevelOptions struct {
+	// 本地缓存最大条目数，默认10000
+	MaxEntries int
+	// 本地缓存最大字节数（key和value的长度之和），默认64MB
+	MaxBytes int64
+	// 本地缓存时间，也是收不到失效通知时的最长不一致时间，默认1分钟
+	LocalTTL time.Duration
+	// 失效通知的频道，同一份数据的所有实例必须一致，默认 xcache:invalidate
+	Channel string
+}
+
+// CacheStats 二级缓存的命中统计
+type CacheStats struct {
+	LocalHits   int64 // 本地缓存命中
+	LocalMisses int64 // 本地缓存未命中
+	RedisHits   int64 // 本地未命中、Redis命中
+	RedisMisses int64 // Redis也未命中
+	Evictions   int64 // 本地缓存因容量淘汰的条目数
+	Entries     int   // 本地缓存条目数
+	Bytes       int64 // 本地缓存字节数
+}
+
+// TwoLevelCache 在Redis前增加进程内LRU缓存，写入和删除通过Redis pub/sub通知其他实例失效本地缓存
+type TwoLevelCache struct {
+	redis      *RedisClient
+	local      *lruCache
+	localTTL   time.Duration
+	channel    string
+	instanceID string
+	// 每次本地失效时递增，防止回填Redis中读到的旧值
+	generation atomic.Uint64
+
+	localHits   atomic.Int64
+	localMisses atomic.Int64
+	redisHits   atomic.Int64
+	redisMisses atomic.Int64
+
+	pubsub    *redis.PubSub
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewTwoLevelCache 创建二级缓存并订阅失效通知，使用完毕后调用 Close
+func NewTwoLevelCache(r *RedisClient, opts TwoLevelOptions) (*TwoLevelCache, error) {
+	if r == nil {
+		return nil, errors.New("redis client is nil")
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 64 << 20
+	}
+	if opts.LocalTTL <= 0 {
+		opts.LocalTTL = time.Minute
+	}
+	if opts.Channel == "" {
+		opts.Channel = "xcache:invalidate"
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := r.rdb.Subscribe(ctx, opts.Channel)
+	// 等待订阅成功，避免订阅前的失效通知丢失
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	c := &TwoLevelCache{
+		redis:      r,
+		local:      newLRUCache(opts.MaxEntries, opts.MaxBytes),
+		localTTL:   opts.LocalTTL,
+		channel:    opts.Channel,
+		instanceID: hex.EncodeToString(id),
+		pubsub:     pubsub,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	go c.listen(ctx)
+	return c, nil
+}
+
+// GetContext 依次读取本地缓存和Redis，Redis命中后回填本地缓存，本地缓存时间不超过key在Redis中的剩余过期时间
+func (c *TwoLevelCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if v, ok := c.local.get(key); ok {
+		c.localHits.Add(1)
+		// 返回副本，避免调用方修改本地缓存内容
+		return append([]byte(nil), v...), nil
+	}
+	c.localMisses.Add(1)
+
+	generation := c.generation.Load()
+	v, ttl, err := c.redis.getWithTTL(ctx, key)
+	if err != nil {
+		if errors.Is(err, ErrCacheMiss) {
+			c.redisMisses.Add(1)
+		}
+		return nil, err
+	}
+	c.redisHits.Add(1)
+	// Redis中key过期时没有失效通知，本地缓存时间不能超过key的剩余过期时间
+	localTTL := c.localTTL
+	if ttl != NoExpiration {
+		localTTL = min(localTTL, ttl)
+	}
+	// 读取期间发生过失效时不回填，下次读取再从Redis获取
+	if c.generation.Load() == generation {
+		c.local.set(key, append([]byte(nil), v...), localTTL)
+	}
+	return v, nil
+}
+
+// SetContext 写入Redis并通知所有实例失效本地缓存
+func (c *TwoLevelCache) SetContext(ctx context.Context, key string, value any, expiration time.Duration) error {
+	if err := c.redis.SetContext(ctx, key, value, expiration); err != nil {
+		return err
+	}
+	return c.Invalidate(ctx, key)
+}
+
+// ExistsContext 本地缓存存在时直接返回，否则查询Redis
+func (c *TwoLevelCache) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if _, ok := c.local.get(key); ok {
+		return true, nil
+	}
+	return c.redis.ExistsContext(ctx, key)
+}
+
+// DeleteContext 删除Redis中的key并通知所有实例失效本地缓存
+func (c *TwoLevelCache) DeleteContext(ctx context.Context, key string) (bool, error) {
+	deleted, err := c.redis.DeleteContext(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return deleted, c.Invalidate(ctx, key)
+}
+
+// TTLContext 返回Redis中key的剩余过期时间
+func (c *TwoLevelCache) TTLContext(ctx context.Context, key string) (time.Duration, error) {
+	return c.redis.TTLContext(ctx, key)
+}
+
+// ExpireContext 设置Redis中key的过期时间并通知所有实例失效本地缓存
+func (c *TwoLevelCache) ExpireContext(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	ok, err := c.redis.ExpireContext(ctx, key, expiration)
+	if err != nil {
+		return false, err
+	}
+	return ok, c.Invalidate(ctx, key)
+}
+
+// IncrByContext 在Redis中自增并通知所有实例失效本地缓存
+func (c *TwoLevelCache) IncrByContext(ctx context.Context, key string, delta int64) (int64, error) {
+	v, err := c.redis.IncrByContext(ctx, key, delta)
+	if err != nil {
+		return 0, err
+	}
+	return v, c.Invalidate(ctx, key)
+}
+
+// TryLock 使用Redis加锁，锁不经过本地缓存
+func (c *TwoLevelCache) TryLock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	return c.redis.TryLock(ctx, key, ttl)
+}
+
+// Invalidate 失效本地缓存并通知其他实例，用于绕过本缓存直接修改Redis数据的场景
+func (c *TwoLevelCache) Invalidate(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		c.invalidateLocal(key)
+		if err := c.redis.rdb.Publish(ctx, c.channel, c.instanceID+"|"+key).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats 返回命中统计
+func (c *TwoLevelCache) Stats() CacheStats {
+	entries, bytes, evictions := c.local.stats()
+	return CacheStats{
+		LocalHits:   c.localHits.Load(),
+		LocalMisses: c.localMisses.Load(),
+		RedisHits:   c.redisHits.Load(),
+		RedisMisses: c.redisMisses.Load(),
+		Evictions:   evictions,
+		Entries:     entries,
+		Bytes:       bytes,
+	}
+}
+
+// Close 取消订阅并清空本地缓存
+func (c *TwoLevelCache) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.cancel()
+		err = c.pubsub.Close()
+		<-c.done
+		c.local.purge()
+	})
+	return err
+}
+
+func (c *TwoLevelCache) invalidateLocal(key string) {
+	c.generation.Add(1)
+	c.local.delete(key)
+}
+
+func (c *TwoLevelCache) purgeLocal() {
+	c.generation.Add(1)
+	c.local.purge()
+}
+
+// listen 处理失效通知。连接断开期间可能丢失通知，重新订阅后清空本地缓存
+func (c *TwoLevelCache) listen(ctx context.Context) {
+	defer close(c.done)
+	for {
+		msg, err := c.pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.redis.logger.Error().Str("channel", c.channel).Err(err).Msg("receive cache invalidation failed")
+			c.purgeLocal()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if m.Kind == "subscribe" {
+				c.purgeLocal()
+			}
+		case *redis.Message:
+			instanceID, key, ok := strings.Cut(m.Payload, "|")
+			// 本实例发出的通知已经在本地处理
+			if ok && instanceID != c.instanceID {
+				c.invalidateLocal(key)
+			}
+		}
+	}
+}
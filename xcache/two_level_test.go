@@ -0,0 +1,122 @@
+package xcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoLevelCache(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	a, err := NewTwoLevelCache(client, TwoLevelOptions{})
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := NewTwoLevelCache(client, TwoLevelOptions{})
+	require.NoError(t, err)
+	defer b.Close()
+
+	mr.Set("config", `"v1"`)
+	v, err := GetJSON[string](ctx, b, "config")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+
+	// 本地缓存命中，不再访问Redis
+	mr.Set("config", `"changed outside"`)
+	v, err = GetJSON[string](ctx, b, "config")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v)
+	assert.Equal(t, int64(1), b.Stats().LocalHits)
+	assert.Equal(t, int64(1), b.Stats().RedisHits)
+
+	// 修改返回值不影响本地缓存
+	raw, err := b.GetContext(ctx, "config")
+	require.NoError(t, err)
+	raw[1] = 'x'
+	raw, err = b.GetContext(ctx, "config")
+	require.NoError(t, err)
+	assert.Equal(t, `"v1"`, string(raw))
+
+	// 其他实例写入后通过pub/sub失效本地缓存
+	require.NoError(t, SetJSON(ctx, a, "config", "v2", time.Minute))
+	assert.Eventually(t, func() bool {
+		v, err := GetJSON[string](ctx, b, "config")
+		return err == nil && v == "v2"
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = a.DeleteContext(ctx, "config")
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := b.GetContext(ctx, "config")
+		return err == ErrCacheMiss
+	}, time.Second, 10*time.Millisecond)
+	assert.Positive(t, b.Stats().RedisMisses)
+}
+
+func TestTwoLevelCacheRedisTTL(t *testing.T) {
+	client, mr := newTestRedisClient(t)
+	ctx := context.Background()
+
+	c, err := NewTwoLevelCache(client, TwoLevelOptions{})
+	require.NoError(t, err)
+	defer c.Close()
+	now := time.Now()
+	c.local.now = func() time.Time { return now }
+
+	require.NoError(t, client.SetContext(ctx, "token", "t1", 5*time.Second))
+	require.NoError(t, client.SetContext(ctx, "config", "v1", 0))
+	v, err := c.GetContext(ctx, "token")
+	require.NoError(t, err)
+	assert.Equal(t, "t1", string(v))
+	_, err = c.GetContext(ctx, "config")
+	require.NoError(t, err)
+
+	// Redis中的key过期后本地缓存同时过期，不会继续返回旧值
+	mr.FastForward(5 * time.Second)
+	now = now.Add(5*time.Second + time.Millisecond)
+	_, err = c.GetContext(ctx, "token")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	exists, err := c.ExistsContext(ctx, "token")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// 不过期的key使用本地缓存时间
+	mr.Set("config", "changed outside")
+	v, err = c.GetContext(ctx, "config")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(v))
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(2, 0)
+	c.set("a", []byte("1"), 0)
+	c.set("b", []byte("2"), 0)
+	_, _ = c.get("a")
+	c.set("c", []byte("3"), 0)
+
+	_, ok := c.get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	_, ok = c.get("a")
+	assert.True(t, ok)
+
+	// 按字节数限制
+	c = newLRUCache(0, 10)
+	c.set("a", []byte("12345"), 0)
+	c.set("b", []byte("12345"), 0)
+	entries, bytes, evictions := c.stats()
+	assert.Equal(t, 1, entries)
+	assert.Equal(t, int64(6), bytes)
+	assert.Equal(t, int64(1), evictions)
+
+	// 过期
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	c.set("ttl", []byte("v"), time.Second)
+	now = now.Add(2 * time.Second)
+	_, ok = c.get("ttl")
+	assert.False(t, ok)
+}
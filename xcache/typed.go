@@ -0,0 +1,80 @@
+package xcache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetAs 获取缓存并使用codec解码为T，不存在时返回 ErrCacheMiss
+func GetAs[T any](ctx context.Context, c Cache, codec Codec, key string) (T, error) {
+	var v T
+	raw, err := c.GetContext(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	err = codec.Unmarshal(raw, &v)
+	return v, err
+}
+
+// SetAs 使用codec编码后设置缓存
+func SetAs(ctx context.Context, c Cache, codec Codec, key string, v any, expiration time.Duration) error {
+	raw, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.SetContext(ctx, key, raw, expiration)
+}
+
+// GetJSON 获取JSON格式的缓存，不存在时返回 ErrCacheMiss
+func GetJSON[T any](ctx context.Context, c Cache, key string) (T, error) {
+	return GetAs[T](ctx, c, JSONCodec, key)
+}
+
+// SetJSON 以JSON格式设置缓存
+func SetJSON(ctx context.Context, c Cache, key string, v any, expiration time.Duration) error {
+	return SetAs(ctx, c, JSONCodec, key, v, expiration)
+}
+
+// MGetAs 批量获取缓存并使用codec解码，返回存在的key及其值
+func MGetAs[T any](ctx context.Context, r *RedisClient, codec Codec, keys ...string) (map[string]T, error) {
+	raws, err := r.MGetContext(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]T, len(keys))
+	for i, raw := range raws {
+		if raw == nil {
+			continue
+		}
+		var v T
+		if err := codec.Unmarshal(raw, &v); err != nil {
+			return nil, fmt.Errorf("decode key %s failed: %w", keys[i], err)
+		}
+		result[keys[i]] = v
+	}
+	return result, nil
+}
+
+// MSetAs 使用codec编码后批量设置缓存
+func MSetAs[T any](ctx context.Context, r *RedisClient, codec Codec, values map[string]T, expiration time.Duration) error {
+	raws := make(map[string]any, len(values))
+	for k, v := range values {
+		raw, err := codec.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("encode key %s failed: %w", k, err)
+		}
+		raws[k] = raw
+	}
+	return r.MSetContext(ctx, raws, expiration)
+}
+
+// MGetJSON 批量获取JSON格式的缓存，返回存在的key及其值
+func MGetJSON[T any](ctx context.Context, r *RedisClient, keys ...string) (map[string]T, error) {
+	return MGetAs[T](ctx, r, JSONCodec, keys...)
+}
+
+// MSetJSON 以JSON格式批量设置缓存
+func MSetJSON[T any](ctx context.Context, r *RedisClient, values map[string]T, expiration time.Duration) error {
+	return MSetAs(ctx, r, JSONCodec, values, expiration)
+}
@@ -10,6 +10,7 @@ const (
 	CodeTooManyRequests = 10005
 	CodeServerBusy      = 10006
 	CodeRequestRejected = 10007
+	CodeRequestTooLarge = 10008
 
 	// 服务内部错误码 11000-11999
 	CodeParamError         = 11000
@@ -24,15 +25,16 @@ const (
 // 预定义错误
 var (
 	// 系统级错误码
-	Success         = New(CodeSuccess, "success")                   // 成功
-	SystemError     = New(CodeSystemError, "system error")          // 系统错误
-	Unauthorized    = New(CodeUnauthorized, "unauthorized")         // 未授权
-	Forbidden       = New(CodeForbidden, "forbidden")               // 禁止访问
-	MethodNotAllow  = New(CodeMethodNotAllow, "method not allowed") // 方法不允许
-	Timeout         = New(CodeTimeout, "timeout")                   // 超时
-	TooManyRequests = New(CodeTooManyRequests, "too many requests") // 请求过多
-	ServerBusy      = New(CodeServerBusy, "server is busy")         // 服务器繁忙
-	RequestRejected = New(CodeRequestRejected, "request rejected")  // 请求被拒绝
+	Success         = New(CodeSuccess, "success")                        // 成功
+	SystemError     = New(CodeSystemError, "system error")               // 系统错误
+	Unauthorized    = New(CodeUnauthorized, "unauthorized")              // 未授权
+	Forbidden       = New(CodeForbidden, "forbidden")                    // 禁止访问
+	MethodNotAllow  = New(CodeMethodNotAllow, "method not allowed")      // 方法不允许
+	Timeout         = New(CodeTimeout, "timeout")                        // 超时
+	TooManyRequests = New(CodeTooManyRequests, "too many requests")      // 请求过多
+	ServerBusy      = New(CodeServerBusy, "server is busy")              // 服务器繁忙
+	RequestRejected = New(CodeRequestRejected, "request rejected")       // 请求被拒绝
+	RequestTooLarge = New(CodeRequestTooLarge, "request body too large") // 请求体过大
 
 	// 服务内部错误码 11000
 	ParamError         = New(CodeParamError, "parameter error")              // 参数错误
@@ -0,0 +1,38 @@
+package xhttp
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RichXan/xcommon/xutil"
+)
+
+// RequestIDTransport 将context中的请求ID写入下游请求的请求头
+type RequestIDTransport struct {
+	// 底层Transport，为nil时使用 http.DefaultTransport
+	Base http.RoundTripper
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	requestID := xutil.RequestIDFromContext(req.Context())
+	if requestID == "" || req.Header.Get(xutil.RequestIDHeader) != "" {
+		return base.RoundTrip(req)
+	}
+	// RoundTripper 不能修改原请求
+	req = req.Clone(req.Context())
+	req.Header.Set(xutil.RequestIDHeader, requestID)
+	return base.RoundTrip(req)
+}
+
+// NewClient 创建传递请求ID的HTTP客户端，发送请求时需使用 http.NewRequestWithContext 传入请求的context
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &RequestIDTransport{},
+	}
+}
@@ -0,0 +1,32 @@
+package xhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientPropagatesRequestID(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(xutil.RequestIDHeader)
+	}))
+	defer server.Close()
+
+	ctx := xutil.WithRequestID(context.Background(), "req-1")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := NewClient(time.Second).Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "req-1", <-received)
+	assert.Empty(t, req.Header.Get(xutil.RequestIDHeader))
+}
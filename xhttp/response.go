@@ -9,6 +9,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// ErrorCodeKey 错误响应的业务编码在gin上下文中的键名，供日志和监控中间件读取
+const ErrorCodeKey = "error_code"
+
 // APIResponse 标准响应结构
 type APIResponse struct {
 	Code    int         `json:"code"`               // 业务编码
@@ -40,27 +43,42 @@ func Success(c *gin.Context, data interface{}) {
 
 // Error 错误响应
 func Error(c *gin.Context, err error) {
-	var resp *APIResponse
-	if e, ok := err.(*xerror.Error); ok {
-		resp = &APIResponse{
-			Code:    e.Code,
-			Message: e.Message,
-		}
-	} else {
-		resp = &APIResponse{
-			Code:    xerror.SystemError.Code,
-			Message: err.Error(),
-		}
-	}
+	resp := newErrorResponse(err)
 	if traceID := c.GetString("trace_id"); traceID != "" {
 		resp.TraceID = traceID
 	}
 
+	c.Set(ErrorCodeKey, resp.Code)
+
 	// 根据错误码设置 HTTP 状态码
 	httpStatus := getHTTPStatus(resp.Code)
 	c.JSON(httpStatus, resp)
 }
 
+// ErrorWithStatus 使用指定的 HTTP 状态码返回错误响应
+func ErrorWithStatus(c *gin.Context, httpStatus int, err error) {
+	resp := newErrorResponse(err)
+	if traceID := c.GetString("trace_id"); traceID != "" {
+		resp.TraceID = traceID
+	}
+	c.Set(ErrorCodeKey, resp.Code)
+	c.JSON(httpStatus, resp)
+}
+
+// newErrorResponse 将错误转换为响应结构
+func newErrorResponse(err error) *APIResponse {
+	if e, ok := err.(*xerror.Error); ok {
+		return &APIResponse{
+			Code:    e.Code,
+			Message: e.Message,
+		}
+	}
+	return &APIResponse{
+		Code:    xerror.SystemError.Code,
+		Message: err.Error(),
+	}
+}
+
 // getHTTPStatus 根据错误码获取 HTTP 状态码
 func getHTTPStatus(code int) int {
 	switch code {
@@ -74,6 +92,8 @@ func getHTTPStatus(code int) int {
 		return http.StatusMethodNotAllowed
 	case xerror.TooManyRequests.Code:
 		return http.StatusTooManyRequests
+	case xerror.RequestTooLarge.Code:
+		return http.StatusRequestEntityTooLarge
 	default:
 		return http.StatusOK
 	}
@@ -0,0 +1,126 @@
+package xhttp
+
+import (
+	"bytes"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ZeroTimePolicy 零值时间的序列化策略
+type ZeroTimePolicy int
+
+const (
+	// ZeroTimeEmpty 零值时间序列化为空字符串 ""
+	ZeroTimeEmpty ZeroTimePolicy = iota
+	// ZeroTimeNull 零值时间序列化为 null
+	ZeroTimeNull
+	// ZeroTimeFormat 零值时间按布局正常格式化
+	ZeroTimeFormat
+)
+
+// DefaultTimeLayout 默认的时间布局
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// TimeFormat 时间序列化配置
+type TimeFormat struct {
+	Layout   string         // 时间布局，默认为 DefaultTimeLayout
+	Location *time.Location // 输出时区，为nil时使用 time.Local
+	Zero     ZeroTimePolicy // 零值时间的处理策略
+}
+
+var timeFormat atomic.Pointer[TimeFormat]
+
+func init() {
+	SetTimeFormat(TimeFormat{})
+}
+
+// SetTimeFormat 设置全局的时间序列化配置，应在服务启动时调用
+func SetTimeFormat(format TimeFormat) {
+	if format.Layout == "" {
+		format.Layout = DefaultTimeLayout
+	}
+	if format.Location == nil {
+		format.Location = time.Local
+	}
+	timeFormat.Store(&format)
+}
+
+// GetTimeFormat 获取当前的时间序列化配置
+func GetTimeFormat() TimeFormat {
+	return *timeFormat.Load()
+}
+
+// Time 按全局 TimeFormat 序列化的时间类型，用于响应结构体中的时间字段
+type Time time.Time
+
+// NewTime 创建 Time
+func NewTime(t time.Time) Time {
+	return Time(t)
+}
+
+// Now 返回当前时间
+func Now() Time {
+	return Time(time.Now())
+}
+
+// Time 转换为 time.Time
+func (t Time) Time() time.Time {
+	return time.Time(t)
+}
+
+// IsZero 是否为零值时间
+func (t Time) IsZero() bool {
+	return time.Time(t).IsZero()
+}
+
+// String 按全局布局格式化时间
+func (t Time) String() string {
+	format := GetTimeFormat()
+	return time.Time(t).In(format.Location).Format(format.Layout)
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (t Time) MarshalJSON() ([]byte, error) {
+	format := GetTimeFormat()
+	if t.IsZero() {
+		switch format.Zero {
+		case ZeroTimeEmpty:
+			return []byte(`""`), nil
+		case ZeroTimeNull:
+			return []byte("null"), nil
+		}
+	}
+	b := make([]byte, 0, len(format.Layout)+2)
+	b = append(b, '"')
+	b = time.Time(t).In(format.Location).AppendFormat(b, format.Layout)
+	b = append(b, '"')
+	return b, nil
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler，支持全局布局和RFC3339格式，空字符串和null解析为零值
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Time{}
+		return nil
+	}
+	s, err := strconv.Unquote(string(data))
+	if err != nil {
+		return err
+	}
+	if s == "" {
+		*t = Time{}
+		return nil
+	}
+	format := GetTimeFormat()
+	parsed, err := time.ParseInLocation(format.Layout, s, format.Location)
+	if err != nil {
+		var rfcErr error
+		parsed, rfcErr = time.Parse(time.RFC3339Nano, s)
+		if rfcErr != nil {
+			return err
+		}
+	}
+	*t = Time(parsed)
+	return nil
+}
@@ -0,0 +1,53 @@
+package xhttp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeMarshalJSON(t *testing.T) {
+	t.Cleanup(func() { SetTimeFormat(TimeFormat{}) })
+
+	shanghai := time.FixedZone("CST", 8*3600)
+	SetTimeFormat(TimeFormat{Location: shanghai})
+
+	type resp struct {
+		CreatedAt Time   `json:"created_at"`
+		DeletedAt Time   `json:"deleted_at"`
+		Remark    string `json:"remark"`
+	}
+	raw, err := json.Marshal(resp{
+		CreatedAt: NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		Remark:    "2024-01-02T03:04:05Z",
+	})
+	require.NoError(t, err)
+	// 用户数据中的时间字符串保持不变
+	assert.JSONEq(t, `{"created_at":"2024-01-02 11:04:05","deleted_at":"","remark":"2024-01-02T03:04:05Z"}`, string(raw))
+
+	SetTimeFormat(TimeFormat{Layout: time.RFC3339, Location: time.UTC, Zero: ZeroTimeNull})
+	raw, err = json.Marshal(resp{CreatedAt: NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, shanghai))})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"created_at":"2024-01-01T19:04:05Z","deleted_at":null,"remark":""}`, string(raw))
+}
+
+func TestTimeUnmarshalJSON(t *testing.T) {
+	t.Cleanup(func() { SetTimeFormat(TimeFormat{}) })
+	SetTimeFormat(TimeFormat{Location: time.UTC})
+
+	var v struct {
+		A Time `json:"a"`
+		B Time `json:"b"`
+		C Time `json:"c"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"a":"2024-01-02 03:04:05","b":"2024-01-02T03:04:05Z","c":""}`), &v))
+	expected := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.True(t, expected.Equal(v.A.Time()))
+	assert.True(t, expected.Equal(v.B.Time()))
+	assert.True(t, v.C.IsZero())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"a":"not a time"}`), &v))
+}
@@ -1,6 +1,8 @@
 package xlog
 
 import (
+	"io"
+
 	"github.com/rs/zerolog"
 )
 
@@ -33,6 +35,10 @@ func (l *Logger) Info() *zerolog.Event {
 	return l.doLogEvent(l.zeroLoger.Info)
 }
 
+func (l *Logger) Warn() *zerolog.Event {
+	return l.doLogEvent(l.zeroLoger.Warn)
+}
+
 func (l *Logger) Error() *zerolog.Event {
 	return l.doLogEvent(l.zeroLoger.Error)
 }
@@ -58,3 +64,12 @@ func NewLogger(cfg LoggerConfig) *Logger {
 		Config: cfg,  // This will now contain the updated LoggerName
 	}
 }
+
+// NewLoggerWithWriter 创建输出到w的日志，只对当前日志设置级别，不修改全局日志级别和包级别的日志函数
+func NewLoggerWithWriter(w io.Writer, level zerolog.Level) *Logger {
+	zl := zerolog.New(w).Level(level).With().Timestamp().Logger()
+	return &Logger{
+		zeroLoger: &zl,
+		Config:    LoggerConfig{Level: level.String(), LoggerName: DefaultLoggerName},
+	}
+}
@@ -0,0 +1,174 @@
+package xmiddleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyIDKey 当前调用方凭证ID在gin上下文中的键名
+const APIKeyIDKey = "api_key_id"
+
+// API Key认证失败的错误，业务码均为 xerror.CodeUnauthorized
+var (
+	ErrAPIKeyRequired = xerror.New(xerror.CodeUnauthorized, "api key is required")
+	ErrAPIKeyInvalid  = xerror.New(xerror.CodeUnauthorized, "invalid api key")
+	ErrAPIKeyExpired  = xerror.New(xerror.CodeUnauthorized, "api key has expired")
+)
+
+// APIKey 合作方或内部服务的调用凭证。
+// Scopes 作为权限写入上下文，可以配合 RequirePermissions 检查
+type APIKey struct {
+	ID       string
+	UserID   string
+	Username string
+	Scopes   []string
+	// HMAC签名密钥，只用于签名认证，需要可逆存储
+	Secret    []byte
+	ExpiresAt time.Time
+	Disabled  bool
+}
+
+// usable 凭证是否可用
+func (k *APIKey) usable(now time.Time) *xerror.Error {
+	if k.Disabled {
+		return ErrAPIKeyInvalid
+	}
+	if !k.ExpiresAt.IsZero() && now.After(k.ExpiresAt) {
+		return ErrAPIKeyExpired
+	}
+	return nil
+}
+
+// APIKeyStore 查询调用凭证，未找到时返回 nil, nil
+type APIKeyStore interface {
+	// GetAPIKeyByHash 按 HashAPIKey 的结果查询，数据库中只保存哈希值
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	// GetAPIKeyByID 按凭证ID查询，用于HMAC签名认证
+	GetAPIKeyByID(ctx context.Context, id string) (*APIKey, error)
+}
+
+// HashAPIKey 计算API Key的哈希值，用于存储和查询
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey 生成新的API Key，返回明文和哈希值，明文只在创建时展示给调用方
+func GenerateAPIKey(prefix string) (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	key := prefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+// MemoryAPIKeyStore 基于内存的凭证存储，用于测试和少量固定凭证
+type MemoryAPIKeyStore struct {
+	mu     sync.RWMutex
+	byHash map[string]*APIKey
+	byID   map[string]*APIKey
+}
+
+// NewMemoryAPIKeyStore 创建内存凭证存储
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{
+		byHash: make(map[string]*APIKey),
+		byID:   make(map[string]*APIKey),
+	}
+}
+
+// Add 添加凭证，hash为空表示只用于HMAC签名认证
+func (s *MemoryAPIKeyStore) Add(hash string, key *APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hash != "" {
+		s.byHash[hash] = key
+	}
+	s.byID[key.ID] = key
+}
+
+func (s *MemoryAPIKeyStore) GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byHash[hash], nil
+}
+
+func (s *MemoryAPIKeyStore) GetAPIKeyByID(ctx context.Context, id string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.byID[id], nil
+}
+
+type apiKeyOptions struct {
+	header string
+}
+
+// APIKeyOption API Key认证配置项
+type APIKeyOption func(*apiKeyOptions)
+
+// WithAPIKeyHeader 读取API Key的请求头，默认 X-API-Key
+func WithAPIKeyHeader(header string) APIKeyOption {
+	return func(o *apiKeyOptions) {
+		o.header = header
+	}
+}
+
+// APIKeyAuth API Key认证中间件，认证成功后设置与 Auth 相同的用户上下文
+func APIKeyAuth(store APIKeyStore, opts ...APIKeyOption) gin.HandlerFunc {
+	if store == nil {
+		panic("api key store is nil")
+	}
+	o := &apiKeyOptions{header: APIKeyHeader}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(o.header)
+		if key == "" {
+			xhttp.Error(c, ErrAPIKeyRequired)
+			c.Abort()
+			return
+		}
+		apiKey, err := store.GetAPIKeyByHash(c.Request.Context(), HashAPIKey(key))
+		if err != nil {
+			xhttp.ErrorWithStatus(c, http.StatusInternalServerError, xerror.Wrap(err, xerror.CodeSystemError, "get api key failed"))
+			c.Abort()
+			return
+		}
+		if apiKey == nil {
+			xhttp.Error(c, ErrAPIKeyInvalid)
+			c.Abort()
+			return
+		}
+		if e := apiKey.usable(time.Now()); e != nil {
+			xhttp.Error(c, e)
+			c.Abort()
+			return
+		}
+
+		setAPIKeyContext(c, apiKey)
+		c.Next()
+	}
+}
+
+// setAPIKeyContext 设置与 Auth 相同的用户上下文，凭证的Scopes作为权限
+func setAPIKeyContext(c *gin.Context, apiKey *APIKey) {
+	c.Set(AuthUserIdKey, apiKey.UserID)
+	c.Set(AuthUsernameKey, apiKey.Username)
+	c.Set(AuthRolesKey, []string(nil))
+	c.Set(AuthPermsKey, apiKey.Scopes)
+	c.Set(APIKeyIDKey, apiKey.ID)
+}
@@ -0,0 +1,105 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := NewMemoryAPIKeyStore()
+	key, hash, err := GenerateAPIKey("pk_")
+	require.NoError(t, err)
+	store.Add(hash, &APIKey{ID: "k1", UserID: "partner", Username: "partner", Scopes: []string{"orders:read"}})
+	expiredKey, expiredHash, err := GenerateAPIKey("pk_")
+	require.NoError(t, err)
+	store.Add(expiredHash, &APIKey{ID: "k2", UserID: "partner", ExpiresAt: time.Now().Add(-time.Hour)})
+
+	r := gin.New()
+	r.Use(APIKeyAuth(store))
+	r.GET("/orders", RequirePermissions("orders:read"), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(AuthUserIdKey)+":"+c.GetString(APIKeyIDKey))
+	})
+	r.DELETE("/orders", RequirePermissions("orders:write"), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	serve := func(method, apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/orders", nil)
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(http.MethodGet, key)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partner:k1", w.Body.String())
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, key).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, key+"x").Code)
+	expired := serve(http.MethodGet, expiredKey)
+	assert.Equal(t, http.StatusUnauthorized, expired.Code)
+	assert.Contains(t, expired.Body.String(), ErrAPIKeyExpired.Message)
+}
+
+func TestHMACAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+	store := NewMemoryAPIKeyStore()
+	store.Add("", &APIKey{ID: "cron", UserID: "cron", Secret: []byte("secret")})
+
+	r := gin.New()
+	r.Use(HMACAuth(HMACAuthOptions{Store: store, Client: client}))
+	r.POST("/jobs", func(c *gin.Context) {
+		body, _ := c.GetRawData()
+		c.String(http.StatusOK, c.GetString(AuthUserIdKey)+":"+string(body))
+	})
+
+	newRequest := func(secret string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/jobs?b=2&a=1", strings.NewReader(`{"job":"sync"}`))
+		require.NoError(t, xhttp.NewSigner("cron", []byte(secret)).Sign(req))
+		return req
+	}
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	req := newRequest("secret")
+	replay := req.Clone(req.Context())
+	replay.Body, _ = req.GetBody()
+
+	w := serve(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `cron:{"job":"sync"}`, w.Body.String())
+
+	// 相同的随机数不能重复使用
+	w = serve(replay)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), ErrNonceReused.Message)
+
+	assert.Equal(t, http.StatusUnauthorized, serve(newRequest("wrong")).Code)
+
+	tampered := newRequest("secret")
+	tampered.URL.RawQuery = "a=1&b=3"
+	assert.Equal(t, http.StatusUnauthorized, serve(tampered).Code)
+
+	stale := newRequest("secret")
+	stale.Header.Set(xhttp.SignTimestampHeader, "1700000000")
+	w = serve(stale)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), ErrSignatureExpired.Message)
+}
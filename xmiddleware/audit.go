@@ -0,0 +1,308 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// AuditChangesKey 处理函数设置的变更内容在gin上下文中的键名
+	AuditChangesKey = "audit_changes"
+	// AuditActionKey 处理函数设置的操作名称在gin上下文中的键名
+	AuditActionKey = "audit_action"
+
+	auditRedacted = "***"
+)
+
+// 默认脱敏的字段名，不区分大小写
+var defaultAuditRedactFields = []string{
+	"password", "old_password", "new_password", "secret", "token",
+	"access_token", "refresh_token", "api_key", "private_key", "credential",
+}
+
+// AuditRecord 审计记录，可以直接作为GORM模型使用
+type AuditRecord struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	RequestID  string    `json:"request_id" gorm:"size:64;index"`
+	UserID     string    `json:"user_id" gorm:"size:64;index"`
+	Username   string    `json:"username" gorm:"size:128"`
+	Action     string    `json:"action,omitempty" gorm:"size:128"`
+	Method     string    `json:"method" gorm:"size:16"`
+	Route      string    `json:"route" gorm:"size:255;index"`
+	Path       string    `json:"path" gorm:"size:1024"`
+	ResourceID string    `json:"resource_id,omitempty" gorm:"size:128;index"`
+	IP         string    `json:"ip" gorm:"size:64"`
+	UserAgent  string    `json:"user_agent" gorm:"size:512"`
+	Request    string    `json:"request,omitempty" gorm:"type:text"`
+	Changes    string    `json:"changes,omitempty" gorm:"type:text"`
+	Status     int       `json:"status"`
+	Code       int       `json:"code"`
+	Success    bool      `json:"success"`
+	LatencyMs  int64     `json:"latency_ms"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 审计表名
+func (AuditRecord) TableName() string {
+	return "audit_logs"
+}
+
+// AuditSink 审计记录的写入目标
+type AuditSink interface {
+	Write(ctx context.Context, record *AuditRecord) error
+}
+
+// AuditOptions 审计中间件配置
+type AuditOptions struct {
+	// 写入目标，必填
+	Sink AuditSink
+	// 需要审计的请求方法，默认 POST、PUT、PATCH 和 DELETE
+	Methods []string
+	// 作为资源ID的路径参数，按顺序取第一个非空值，默认 id
+	ResourceParams []string
+	// 需要脱敏的字段名，不区分大小写，默认包含密码、令牌和密钥等字段
+	RedactFields []string
+	// 记录的请求体最大字节数，默认4KB，超出部分截断
+	MaxBodySize int
+	// 不审计的路由
+	SkipPaths []string
+	// 写入超时时间，默认3秒
+	WriteTimeout time.Duration
+	// 记录写入失败的日志，可选
+	Logger *xlog.Logger
+}
+
+// Audit 审计中间件，记录修改类请求的操作人、路由、资源ID、脱敏后的请求内容、结果和业务码。
+// 应放在 Auth 之后；处理函数可以通过 SetAuditAction 和 SetAuditChanges 补充操作名称和变更前后的差异
+func Audit(opts AuditOptions) gin.HandlerFunc {
+	if opts.Sink == nil {
+		panic("audit sink is nil")
+	}
+	if len(opts.Methods) == 0 {
+		opts.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if len(opts.ResourceParams) == 0 {
+		opts.ResourceParams = []string{"id"}
+	}
+	if opts.RedactFields == nil {
+		opts.RedactFields = defaultAuditRedactFields
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 4 << 10
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = 3 * time.Second
+	}
+	methods := make(map[string]struct{}, len(opts.Methods))
+	for _, method := range opts.Methods {
+		methods[method] = struct{}{}
+	}
+	skip := make(map[string]struct{}, len(opts.SkipPaths))
+	for _, path := range opts.SkipPaths {
+		skip[path] = struct{}{}
+	}
+	redactor := newAuditRedactor(opts.RedactFields)
+
+	return func(c *gin.Context) {
+		if _, ok := methods[c.Request.Method]; !ok {
+			c.Next()
+			return
+		}
+		if _, ok := skip[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		var body []byte
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			// 只读取需要记录的部分，其余内容保留给处理函数
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(opts.MaxBodySize)+1))
+			c.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(body), c.Request.Body),
+				Closer: c.Request.Body,
+			}
+		}
+
+		c.Next()
+
+		record := &AuditRecord{
+			RequestID: c.GetString("request_id"),
+			Action:    c.GetString(AuditActionKey),
+			Method:    c.Request.Method,
+			Route:     c.FullPath(),
+			Path:      c.Request.URL.Path,
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Request:   redactor.summarize(c.ContentType(), body, opts.MaxBodySize),
+			Status:    c.Writer.Status(),
+			Code:      c.GetInt(xhttp.ErrorCodeKey),
+			LatencyMs: time.Since(start).Milliseconds(),
+			CreatedAt: start,
+		}
+		record.UserID, record.Username, _ = GetCurrentUser(c)
+		for _, param := range opts.ResourceParams {
+			if v := c.Param(param); v != "" {
+				record.ResourceID = v
+				break
+			}
+		}
+		record.Success = record.Status < http.StatusBadRequest && record.Code == 0
+		if changes, ok := c.Get(AuditChangesKey); ok {
+			record.Changes = redactor.marshal(changes)
+		}
+
+		// 请求可能已被取消，写入审计记录不受影响
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), opts.WriteTimeout)
+		defer cancel()
+		if err := opts.Sink.Write(ctx, record); err != nil && opts.Logger != nil {
+			opts.Logger.Error().Err(err).Str("route", record.Route).Str("user_id", record.UserID).Msg("write audit record failed")
+		}
+	}
+}
+
+// SetAuditAction 设置本次操作的名称，如 "user.disable"
+func SetAuditAction(c *gin.Context, action string) {
+	c.Set(AuditActionKey, action)
+}
+
+// SetAuditChanges 记录资源变更前后的差异，只保存发生变化的顶层字段
+func SetAuditChanges(c *gin.Context, before, after any) {
+	c.Set(AuditChangesKey, diffAuditValues(before, after))
+}
+
+// AuditChange 单个字段的变更
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// diffAuditValues 比较两个对象JSON序列化后的顶层字段
+func diffAuditValues(before, after any) map[string]AuditChange {
+	b, a := toAuditMap(before), toAuditMap(after)
+	changes := make(map[string]AuditChange)
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(bv, av) {
+			changes[k] = AuditChange{Before: bv, After: a[k]}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = AuditChange{After: av}
+		}
+	}
+	return changes
+}
+
+func toAuditMap(v any) map[string]any {
+	m := make(map[string]any)
+	if v == nil {
+		return m
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return m
+	}
+	_ = json.Unmarshal(raw, &m)
+	return m
+}
+
+// auditRedactor 对审计内容中的敏感字段脱敏
+type auditRedactor struct {
+	fields map[string]struct{}
+}
+
+func newAuditRedactor(fields []string) *auditRedactor {
+	r := &auditRedactor{fields: make(map[string]struct{}, len(fields))}
+	for _, field := range fields {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+	return r
+}
+
+// summarize 生成请求内容摘要：JSON和表单脱敏后记录，其他类型只记录类型和大小
+func (r *auditRedactor) summarize(contentType string, body []byte, maxSize int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	truncated := len(body) > maxSize
+	if truncated {
+		body = body[:maxSize]
+	}
+	switch {
+	case strings.Contains(contentType, "json") && !truncated:
+		var v any
+		if err := json.Unmarshal(body, &v); err == nil {
+			return r.marshal(v)
+		}
+	case contentType == "application/x-www-form-urlencoded" && !truncated:
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for k := range values {
+				if r.sensitive(k) {
+					values[k] = []string{auditRedacted}
+				}
+			}
+			return values.Encode()
+		}
+	}
+	// 截断的内容无法可靠脱敏，只记录摘要
+	if truncated {
+		return fmt.Sprintf("[%s, more than %d bytes]", contentType, maxSize)
+	}
+	return fmt.Sprintf("[%s, %d bytes]", contentType, len(body))
+}
+
+func (r *auditRedactor) marshal(v any) string {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return ""
+	}
+	raw, _ = json.Marshal(r.redact(generic))
+	return string(raw)
+}
+
+func (r *auditRedactor) redact(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			if r.sensitive(k) {
+				val[k] = auditRedacted
+				continue
+			}
+			val[k] = r.redact(item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = r.redact(item)
+		}
+	}
+	return v
+}
+
+func (r *auditRedactor) sensitive(field string) bool {
+	_, ok := r.fields[strings.ToLower(field)]
+	return ok
+}
+
+// readCloser 组合Reader和原始请求体的Closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,94 @@
+package xmiddleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/RichXan/xcommon/xlog"
+	"github.com/RichXan/xcommon/xmq"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"gorm.io/gorm"
+)
+
+// GormAuditSink 将审计记录写入数据库的 audit_logs 表
+type GormAuditSink struct {
+	db *gorm.DB
+}
+
+// NewGormAuditSink 创建数据库审计写入器
+func NewGormAuditSink(db *gorm.DB) *GormAuditSink {
+	if db == nil {
+		panic("gorm db is nil")
+	}
+	return &GormAuditSink{db: db}
+}
+
+// AutoMigrate 创建或更新审计表
+func (s *GormAuditSink) AutoMigrate() error {
+	return s.db.AutoMigrate(&AuditRecord{})
+}
+
+func (s *GormAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	return s.db.WithContext(ctx).Create(record).Error
+}
+
+// PulsarAuditSink 将审计记录以JSON格式异步发送到Pulsar主题
+type PulsarAuditSink struct {
+	pulsar *xmq.Pulsar
+	logger *xlog.Logger
+}
+
+// NewPulsarAuditSink 创建Pulsar审计写入器，logger用于记录异步发送失败，可为nil
+func NewPulsarAuditSink(p *xmq.Pulsar, logger *xlog.Logger) *PulsarAuditSink {
+	if p == nil {
+		panic("pulsar is nil")
+	}
+	return &PulsarAuditSink{pulsar: p, logger: logger}
+}
+
+func (s *PulsarAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	if s.pulsar.GetProducer() == nil {
+		return errors.New("pulsar producer is nil")
+	}
+	s.pulsar.AsyncSendJson(record, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+		if err != nil && s.logger != nil {
+			s.logger.Error().Err(err).Str("request_id", record.RequestID).Msg("send audit record failed")
+		}
+	})
+	return nil
+}
+
+// LogAuditSink 将审计记录写入日志
+type LogAuditSink struct {
+	logger *xlog.Logger
+}
+
+// NewLogAuditSink 创建日志审计写入器
+func NewLogAuditSink(logger *xlog.Logger) *LogAuditSink {
+	if logger == nil {
+		panic("logger is nil")
+	}
+	return &LogAuditSink{logger: logger}
+}
+
+func (s *LogAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	s.logger.Info().
+		Str("request_id", record.RequestID).
+		Str("user_id", record.UserID).
+		Str("username", record.Username).
+		Str("action", record.Action).
+		Str("method", record.Method).
+		Str("route", record.Route).
+		Str("path", record.Path).
+		Str("resource_id", record.ResourceID).
+		Str("ip", record.IP).
+		Str("request", record.Request).
+		Str("changes", record.Changes).
+		Int("status", record.Status).
+		Int("code", record.Code).
+		Bool("success", record.Success).
+		Int64("latency_ms", record.LatencyMs).
+		Msg("audit")
+	return nil
+}
@@ -0,0 +1,80 @@
+package xmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []*AuditRecord
+}
+
+func (s *memoryAuditSink) Write(ctx context.Context, record *AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func TestAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sink := &memoryAuditSink{}
+
+	r := gin.New()
+	r.Use(withUser("u1", nil, nil), Audit(AuditOptions{Sink: sink}))
+	r.GET("/users/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.PUT("/users/:id", func(c *gin.Context) {
+		var req map[string]any
+		require.NoError(t, c.ShouldBindJSON(&req))
+		SetAuditAction(c, "user.update")
+		SetAuditChanges(c,
+			gin.H{"name": "old", "password": "a", "age": 1},
+			gin.H{"name": req["name"], "password": req["password"], "age": 1},
+		)
+		xhttp.Success(c, nil)
+	})
+	r.DELETE("/users/:id", func(c *gin.Context) {
+		xhttp.Error(c, xerror.Forbidden)
+	})
+
+	serve := func(method, path, body string) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+	}
+	serve(http.MethodGet, "/users/1", "")
+	serve(http.MethodPut, "/users/1", `{"name":"new","password":"secret","profile":{"token":"t"}}`)
+	serve(http.MethodDelete, "/users/2", "")
+
+	require.Len(t, sink.records, 2)
+	update := sink.records[0]
+	assert.Equal(t, "u1", update.UserID)
+	assert.Equal(t, "u1", update.Username)
+	assert.Equal(t, "user.update", update.Action)
+	assert.Equal(t, "/users/:id", update.Route)
+	assert.Equal(t, "1", update.ResourceID)
+	assert.JSONEq(t, `{"name":"new","password":"***","profile":{"token":"***"}}`, update.Request)
+	assert.JSONEq(t, `{"name":{"before":"old","after":"new"},"password":"***"}`, update.Changes)
+	assert.True(t, update.Success)
+
+	deleted := sink.records[1]
+	assert.Equal(t, "2", deleted.ResourceID)
+	assert.Equal(t, http.StatusForbidden, deleted.Status)
+	assert.Equal(t, xerror.CodeForbidden, deleted.Code)
+	assert.False(t, deleted.Success)
+}
@@ -1,10 +1,13 @@
 package xmiddleware
 
 import (
-	"net/http"
+	"errors"
 	"strings"
 
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
 	xoauth "github.com/RichXan/xcommon/xoauth"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -13,46 +16,119 @@ const (
 	AuthHeaderKey   = "Authorization"
 	AuthUserIdKey   = "user_id"
 	AuthUsernameKey = "username"
+	AuthRolesKey    = "roles"
+	AuthPermsKey    = "permissions"
+	AuthClaimsKey   = "claims"
+
+	// AuthScheme Authorization请求头中的令牌类型
+	AuthScheme = "Bearer"
 )
 
-// Auth 认证中间件
-func Auth(claim xoauth.Claim) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 从请求头中获取token
-		authHeader := c.GetHeader(AuthHeaderKey)
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
-			return
+// 认证失败的错误，业务码均为 xerror.CodeUnauthorized
+var (
+	ErrTokenRequired = xerror.New(xerror.CodeUnauthorized, "authorization token is required")
+	ErrTokenFormat   = xerror.New(xerror.CodeUnauthorized, "authorization header format must be Bearer {token}")
+	ErrTokenExpired  = xerror.New(xerror.CodeUnauthorized, "token has expired")
+	ErrTokenInvalid  = xerror.New(xerror.CodeUnauthorized, "invalid token")
+	ErrTokenRevoked  = xerror.New(xerror.CodeUnauthorized, "token has been revoked")
+)
+
+// TokenExtractor 从请求中提取令牌，未找到时返回空字符串
+type TokenExtractor func(c *gin.Context) (string, error)
+
+// HeaderTokenExtractor 从请求头中提取令牌，scheme不为空时要求格式为 "{scheme} {token}"
+func HeaderTokenExtractor(header, scheme string) TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		value := c.GetHeader(header)
+		if value == "" || scheme == "" {
+			return value, nil
+		}
+		parts := strings.SplitN(value, " ", 2)
+		if !(len(parts) == 2 && strings.EqualFold(parts[0], scheme)) {
+			return "", ErrTokenFormat
 		}
+		return strings.TrimSpace(parts[1]), nil
+	}
+}
 
-		// 检查Authorization格式
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
-			c.Abort()
-			return
+// CookieTokenExtractor 从Cookie中提取令牌
+func CookieTokenExtractor(name string) TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		value, err := c.Cookie(name)
+		if err != nil {
+			return "", nil
 		}
+		return value, nil
+	}
+}
+
+// QueryTokenExtractor 从查询参数中提取令牌
+func QueryTokenExtractor(name string) TokenExtractor {
+	return func(c *gin.Context) (string, error) {
+		return c.Query(name), nil
+	}
+}
+
+// authOptions 认证中间件配置
+type authOptions struct {
+	tokenStore xoauth.TokenStore
+	extractors []TokenExtractor
+}
+
+// AuthOption 认证中间件配置项
+type AuthOption func(*authOptions)
+
+// WithTokenStore 使用TokenStore检查令牌是否已被撤销
+func WithTokenStore(store xoauth.TokenStore) AuthOption {
+	return func(o *authOptions) {
+		o.tokenStore = store
+	}
+}
+
+// WithTokenExtractors 按顺序尝试从多个位置提取令牌，默认只读取 Authorization: Bearer {token}
+func WithTokenExtractors(extractors ...TokenExtractor) AuthOption {
+	return func(o *authOptions) {
+		o.extractors = extractors
+	}
+}
+
+func newAuthOptions(opts []AuthOption) *authOptions {
+	o := &authOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if len(o.extractors) == 0 {
+		o.extractors = []TokenExtractor{HeaderTokenExtractor(AuthHeaderKey, AuthScheme)}
+	}
+	return o
+}
 
-		// 解析token
-		claims, err := claim.ParseAccessToken(parts[1])
+// Auth 认证中间件
+func Auth(claim xoauth.Claim, opts ...AuthOption) gin.HandlerFunc {
+	o := newAuthOptions(opts)
+	return func(c *gin.Context) {
+		claims, err := authenticate(c, claim, o)
 		if err != nil {
-			if err == jwt.ErrTokenExpired {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "Token has expired",
-					"code":  "token_expired",
-				})
-			} else {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			}
+			xhttp.Error(c, err)
 			c.Abort()
 			return
 		}
 
 		// 将用户信息存储到上下文中
-		c.Set(AuthUserIdKey, claims.UserID)
-		c.Set(AuthUsernameKey, claims.Username)
+		setAuthContext(c, claims)
+
+		c.Next()
+	}
+}
 
+// OptionalAuth 可选的认证中间件，令牌缺失或无效时按匿名用户处理
+func OptionalAuth(claim xoauth.Claim, opts ...AuthOption) gin.HandlerFunc {
+	o := newAuthOptions(opts)
+	return func(c *gin.Context) {
+		claims, err := authenticate(c, claim, o)
+		if err == nil {
+			setAuthContext(c, claims)
+		}
 		c.Next()
 	}
 }
@@ -72,30 +148,66 @@ func GetCurrentUser(c *gin.Context) (string, string, bool) {
 	return userID.(string), username.(string), true
 }
 
-// OptionalAuth 可选的认证中间件
-func OptionalAuth(claim xoauth.Claim) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Next()
-			return
-		}
+// GetClaims 从上下文中获取当前令牌的完整Claims
+func GetClaims(c *gin.Context) (*xoauth.Claims, bool) {
+	v, exists := c.Get(AuthClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := v.(*xoauth.Claims)
+	return claims, ok
+}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if !(len(parts) == 2 && parts[0] == "Bearer") {
-			c.Next()
-			return
-		}
+// authenticate 提取并校验令牌
+func authenticate(c *gin.Context, claim xoauth.Claim, o *authOptions) (*xoauth.Claims, *xerror.Error) {
+	token, err := extractToken(c, o.extractors)
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, ErrTokenRequired
+	}
 
-		claims, err := claim.ParseAccessToken(parts[1])
-		if err != nil {
-			c.Next()
-			return
+	claims, parseErr := claim.ParseAccessToken(token)
+	if parseErr != nil {
+		if errors.Is(parseErr, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
 		}
+		return nil, ErrTokenInvalid
+	}
 
-		c.Set(AuthUserIdKey, claims.UserID)
-		c.Set(AuthUsernameKey, claims.Username)
+	if o.tokenStore != nil && o.tokenStore.IsTokenRevoked(c.Request.Context(), claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
 
-		c.Next()
+// extractToken 依次尝试各个提取器，返回第一个非空令牌
+func extractToken(c *gin.Context, extractors []TokenExtractor) (string, *xerror.Error) {
+	var firstErr *xerror.Error
+	for _, extractor := range extractors {
+		token, err := extractor(c)
+		if err != nil {
+			if firstErr == nil {
+				if e, ok := err.(*xerror.Error); ok {
+					firstErr = e
+				} else {
+					firstErr = xerror.Wrap(err, xerror.CodeUnauthorized, ErrTokenInvalid.Message)
+				}
+			}
+			continue
+		}
+		if token != "" {
+			return token, nil
+		}
 	}
+	return "", firstErr
+}
+
+func setAuthContext(c *gin.Context, claims *xoauth.Claims) {
+	c.Set(AuthUserIdKey, claims.UserID)
+	c.Set(AuthUsernameKey, claims.Username)
+	c.Set(AuthRolesKey, claims.Roles)
+	c.Set(AuthPermsKey, claims.Permissions)
+	c.Set(AuthClaimsKey, claims)
 }
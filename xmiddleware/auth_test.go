@@ -0,0 +1,84 @@
+package xmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xoauth "github.com/RichXan/xcommon/xoauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revokedTokenStore 只实现撤销检查的TokenStore
+type revokedTokenStore struct {
+	xoauth.TokenStore
+	revoked map[string]bool
+}
+
+func (s *revokedTokenStore) IsTokenRevoked(ctx context.Context, tokenID string) bool {
+	return s.revoked[tokenID]
+}
+
+func (s *revokedTokenStore) RevokeToken(ctx context.Context, tokenID string, expiration time.Duration) error {
+	s.revoked[tokenID] = true
+	return nil
+}
+
+func newTestClaim(t *testing.T) (xoauth.Claim, string) {
+	claim := xoauth.NewClaims(nil)
+	require.NoError(t, claim.GenerateKeyPair(t.TempDir()))
+	pair, err := claim.GenerateTokenPair(xoauth.Info{UserID: "u1", Username: "alice", Roles: []string{"admin"}})
+	require.NoError(t, err)
+	return claim, pair.AccessToken
+}
+
+func TestAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	claim, token := newTestClaim(t)
+	store := &revokedTokenStore{revoked: map[string]bool{}}
+
+	r := gin.New()
+	r.GET("/me", Auth(claim,
+		WithTokenStore(store),
+		WithTokenExtractors(HeaderTokenExtractor(AuthHeaderKey, AuthScheme), CookieTokenExtractor("token")),
+	), func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		require.True(t, ok)
+		c.String(http.StatusOK, claims.Username)
+	})
+
+	serve := func(setup func(req *http.Request)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		setup(req)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(func(req *http.Request) {})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), ErrTokenRequired.Message)
+
+	w = serve(func(req *http.Request) { req.Header.Set(AuthHeaderKey, "Bearer "+token) })
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+
+	w = serve(func(req *http.Request) { req.AddCookie(&http.Cookie{Name: "token", Value: token}) })
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(func(req *http.Request) { req.Header.Set(AuthHeaderKey, "Bearer invalid") })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), ErrTokenInvalid.Message)
+
+	claims, err := claim.ParseAccessToken(token)
+	require.NoError(t, err)
+	require.NoError(t, store.RevokeToken(context.Background(), claims.ID, time.Hour))
+	w = serve(func(req *http.Request) { req.Header.Set(AuthHeaderKey, "Bearer "+token) })
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), ErrTokenRevoked.Message)
+}
@@ -0,0 +1,83 @@
+package xmiddleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit 限制请求体大小，超过maxBytes时返回413。
+// Content-Length 超限的请求直接拒绝；分块传输的请求在读取超限时返回 xerror.RequestTooLarge，
+// 处理函数未写入响应时由中间件返回413。应放在 Logger 等会读取请求体的中间件之前
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	if maxBytes <= 0 {
+		panic("max body size must be positive")
+	}
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			xhttp.Error(c, xerror.RequestTooLarge)
+			c.Abort()
+			return
+		}
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		body := &limitedBody{r: c.Request.Body, closer: c.Request.Body, remaining: maxBytes}
+		c.Request.Body = body
+
+		c.Next()
+
+		abortIfBodyTooLarge(c, body)
+	}
+}
+
+// limitedBody 限制读取字节数的请求体
+type limitedBody struct {
+	r         io.Reader
+	closer    io.Closer
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, xerror.RequestTooLarge
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// 多读一个字节用于判断是否超限
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.r.Read(p)
+	if err == xerror.RequestTooLarge {
+		b.exceeded = true
+	}
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		return n, err
+	}
+	n = int(b.remaining)
+	b.remaining = 0
+	b.exceeded = true
+	return n, xerror.RequestTooLarge
+}
+
+func (b *limitedBody) Close() error {
+	return b.closer.Close()
+}
+
+// abortIfBodyTooLarge 请求体超限且处理函数没有写入响应时返回413
+func abortIfBodyTooLarge(c *gin.Context, body *limitedBody) {
+	if body.exceeded && !c.Writer.Written() {
+		xhttp.Error(c, xerror.RequestTooLarge)
+		c.Abort()
+	}
+}
@@ -0,0 +1,286 @@
+package xmiddleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 默认压缩的响应类型
+var defaultCompressContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/problem+json",
+	"image/svg+xml",
+}
+
+type compressOptions struct {
+	level        int
+	minSize      int
+	contentTypes []string
+	skipPaths    map[string]struct{}
+}
+
+// CompressOption 响应压缩配置项
+type CompressOption func(*compressOptions)
+
+// WithCompressLevel 压缩级别，默认 gzip.DefaultCompression
+func WithCompressLevel(level int) CompressOption {
+	return func(o *compressOptions) {
+		o.level = level
+	}
+}
+
+// WithCompressMinSize 响应体小于minSize字节时不压缩，默认1024
+func WithCompressMinSize(minSize int) CompressOption {
+	return func(o *compressOptions) {
+		o.minSize = minSize
+	}
+}
+
+// WithCompressContentTypes 需要压缩的响应类型，以 "/" 结尾表示前缀匹配，如 "text/"
+func WithCompressContentTypes(contentTypes ...string) CompressOption {
+	return func(o *compressOptions) {
+		o.contentTypes = contentTypes
+	}
+}
+
+// WithCompressSkipPaths 不压缩的路由，如 SSE 或文件下载接口
+func WithCompressSkipPaths(paths ...string) CompressOption {
+	return func(o *compressOptions) {
+		for _, path := range paths {
+			o.skipPaths[path] = struct{}{}
+		}
+	}
+}
+
+// Compress 根据 Accept-Encoding 使用 gzip 或 deflate 压缩响应
+func Compress(opts ...CompressOption) gin.HandlerFunc {
+	o := &compressOptions{
+		level:        gzip.DefaultCompression,
+		minSize:      1024,
+		contentTypes: defaultCompressContentTypes,
+		skipPaths:    make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.level < gzip.HuffmanOnly || o.level > gzip.BestCompression {
+		panic("invalid compress level: " + strconv.Itoa(o.level))
+	}
+	gzipPool := &sync.Pool{New: func() any {
+		w, _ := gzip.NewWriterLevel(io.Discard, o.level)
+		return w
+	}}
+	zlibPool := &sync.Pool{New: func() any {
+		w, _ := zlib.NewWriterLevel(io.Discard, o.level)
+		return w
+	}}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		if _, ok := o.skipPaths[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			opts:           o,
+			encoding:       encoding,
+			gzipPool:       gzipPool,
+			zlibPool:       zlibPool,
+		}
+		c.Writer = cw
+		defer func() {
+			cw.close()
+			c.Writer = cw.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding 从 Accept-Encoding 中选择压缩算法，优先 gzip
+func negotiateEncoding(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		accepted[name] = q > 0
+	}
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if enabled, ok := accepted[encoding]; ok {
+			if enabled {
+				return encoding
+			}
+			continue
+		}
+		if accepted["*"] {
+			return encoding
+		}
+	}
+	return ""
+}
+
+// compressWriter 缓冲响应体直到达到最小压缩大小，再决定是否压缩
+type compressWriter struct {
+	gin.ResponseWriter
+	opts     *compressOptions
+	encoding string
+	gzipPool *sync.Pool
+	zlibPool *sync.Pool
+
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser
+	release func()
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.opts.minSize {
+			return len(b), nil
+		}
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush 流式响应需要立即输出缓冲的数据
+func (w *compressWriter) Flush() {
+	if !w.decided && len(w.buf) > 0 {
+		_ = w.start()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start 根据响应状态、类型和已有编码决定是否压缩，然后写出缓冲的数据
+func (w *compressWriter) start() error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.shouldCompress() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		// 压缩后内容不同，强校验ETag改为弱校验
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.newEncoder()
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if len(w.buf) < w.opts.minSize {
+		return false
+	}
+	// 处理函数已调用 WriteHeaderNow 时响应头已发出，无法再设置 Content-Encoding
+	if w.ResponseWriter.Written() {
+		return false
+	}
+	status := w.Status()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if mediaType, _, ok := strings.Cut(contentType, ";"); ok {
+		contentType = mediaType
+	}
+	contentType = strings.TrimSpace(contentType)
+	for _, t := range w.opts.contentTypes {
+		if strings.HasSuffix(t, "/") && strings.HasPrefix(contentType, t) || contentType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) newEncoder() {
+	switch w.encoding {
+	case "gzip":
+		gw := w.gzipPool.Get().(*gzip.Writer)
+		gw.Reset(w.ResponseWriter)
+		w.encoder = gw
+		w.release = func() { w.gzipPool.Put(gw) }
+	case "deflate":
+		zw := w.zlibPool.Get().(*zlib.Writer)
+		zw.Reset(w.ResponseWriter)
+		w.encoder = zw
+		w.release = func() { w.zlibPool.Put(zw) }
+	}
+}
+
+// close 写出剩余的缓冲数据并结束压缩流
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.start()
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		w.release()
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,137 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	large := strings.Repeat("hello world ", 200)
+
+	r := gin.New()
+	r.Use(Compress())
+	r.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	})
+	r.GET("/small", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(large))
+	})
+	r.GET("/flushed", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain")
+		c.Writer.WriteHeaderNow()
+		_, _ = c.Writer.WriteString(large)
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "br;q=1.0, gzip;q=0.8")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	gr, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(body))
+
+	assert.Equal(t, "deflate", get("/large", "deflate").Header().Get("Content-Encoding"))
+	assert.Empty(t, get("/large", "gzip;q=0").Header().Get("Content-Encoding"))
+	assert.Empty(t, get("/large", "").Header().Get("Content-Encoding"))
+
+	// 小响应和非文本类型不压缩
+	small := get("/small", "gzip")
+	assert.Empty(t, small.Header().Get("Content-Encoding"))
+	assert.Equal(t, "ok", small.Body.String())
+	image := get("/image", "gzip")
+	assert.Empty(t, image.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, image.Body.String())
+
+	// 响应头已发出时不压缩
+	flushed := get("/flushed", "gzip")
+	assert.Empty(t, flushed.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, flushed.Body.String())
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(BodyLimit(10))
+	r.POST("/", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	post := func(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.ContentLength = contentLength
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusNoContent, post(strings.NewReader("0123456789"), 10).Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(strings.NewReader("0123456789a"), 11).Code)
+	// 分块传输没有 Content-Length，读取时超限
+	w := post(strings.NewReader("0123456789a"), -1)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "request body too large")
+}
+
+func TestDecompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Decompress(WithDecompressMaxSize(4 << 20)))
+	r.POST("/", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	})
+
+	gzipped := func(data []byte) *bytes.Buffer {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, _ = gw.Write(data)
+		_ = gw.Close()
+		return &buf
+	}
+	post := func(body io.Reader, encoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Encoding", encoding)
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(gzipped([]byte(`{"name":"xcommon"}`)), "gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "18", w.Body.String())
+
+	// 压缩炸弹：高压缩比的请求体
+	bomb := post(gzipped(make([]byte, 3<<20)), "gzip")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, bomb.Code)
+
+	assert.Equal(t, http.StatusBadRequest, post(strings.NewReader("not gzip"), "gzip").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, post(strings.NewReader("data"), "br").Code)
+}
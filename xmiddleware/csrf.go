@@ -0,0 +1,187 @@
+package xmiddleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// CSRFCookieName CSRF令牌的Cookie名称，前端读取后放入请求头
+	CSRFCookieName = "csrf_token"
+	// CSRFHeaderName 提交CSRF令牌的请求头
+	CSRFHeaderName = "X-CSRF-Token"
+	// CSRFFormField 表单提交CSRF令牌的字段名
+	CSRFFormField = "_csrf"
+	// CSRFTokenKey 当前请求的CSRF令牌在gin上下文中的键名，用于渲染页面
+	CSRFTokenKey = "csrf_token"
+
+	csrfNonceSize = 16
+)
+
+// CSRF校验失败的错误，业务码为 xerror.CodeForbidden
+var (
+	ErrCSRFTokenMissing = xerror.New(xerror.CodeForbidden, "csrf token is missing")
+	ErrCSRFTokenInvalid = xerror.New(xerror.CodeForbidden, "csrf token is invalid")
+)
+
+// CSRFOptions CSRF中间件配置
+type CSRFOptions struct {
+	// 签名密钥，必填
+	Secret []byte
+	// Cookie名称，默认 csrf_token
+	CookieName string
+	// 请求头名称，默认 X-CSRF-Token
+	HeaderName string
+	// 表单字段名，默认 _csrf
+	FormField string
+	// Cookie属性
+	CookiePath   string
+	CookieDomain string
+	Secure       bool
+	// 默认 http.SameSiteLaxMode
+	SameSite http.SameSite
+	// 令牌有效期，默认12小时
+	TTL time.Duration
+	// 不做校验的路由，如第三方回调
+	ExemptPaths []string
+	// 携带 Authorization 请求头的请求不依赖Cookie认证，默认跳过校验；设置为true时也校验
+	CheckBearerRequests bool
+}
+
+// CSRF 基于签名双重提交Cookie的CSRF防护，用于Cookie认证的管理后台。
+// 令牌与当前会话（令牌ID或用户ID）绑定，应放在 OptionalAuth 之后：
+// 安全方法的请求在令牌缺失、过期或会话变化（如登录）时下发新令牌；
+// 其他方法的请求要求请求头或表单字段中的令牌与Cookie一致且签名有效，否则返回403
+func CSRF(opts CSRFOptions) gin.HandlerFunc {
+	if len(opts.Secret) == 0 {
+		panic("csrf secret is empty")
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = CSRFCookieName
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = CSRFHeaderName
+	}
+	if opts.FormField == "" {
+		opts.FormField = CSRFFormField
+	}
+	if opts.CookiePath == "" {
+		opts.CookiePath = "/"
+	}
+	if opts.SameSite == 0 {
+		opts.SameSite = http.SameSiteLaxMode
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 12 * time.Hour
+	}
+	exempt := make(map[string]struct{}, len(opts.ExemptPaths))
+	for _, path := range opts.ExemptPaths {
+		exempt[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := exempt[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+		if !opts.CheckBearerRequests && c.GetHeader(AuthHeaderKey) != "" {
+			c.Next()
+			return
+		}
+
+		session := csrfSession(c)
+		cookie, _ := c.Cookie(opts.CookieName)
+		valid := cookie != "" && verifyCSRFToken(opts.Secret, cookie, session, time.Now())
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			token := cookie
+			if !valid {
+				token = newCSRFToken(opts.Secret, session, time.Now().Add(opts.TTL))
+				c.SetSameSite(opts.SameSite)
+				// 前端需要读取Cookie放入请求头，不能设置HttpOnly
+				c.SetCookie(opts.CookieName, token, int(opts.TTL/time.Second), opts.CookiePath, opts.CookieDomain, opts.Secure, false)
+			}
+			c.Set(CSRFTokenKey, token)
+			c.Next()
+			return
+		}
+
+		submitted := c.GetHeader(opts.HeaderName)
+		if submitted == "" {
+			submitted = c.PostForm(opts.FormField)
+		}
+		if cookie == "" || submitted == "" {
+			xhttp.Error(c, ErrCSRFTokenMissing)
+			c.Abort()
+			return
+		}
+		if !valid || !hmac.Equal([]byte(cookie), []byte(submitted)) {
+			xhttp.Error(c, ErrCSRFTokenInvalid)
+			c.Abort()
+			return
+		}
+		c.Set(CSRFTokenKey, cookie)
+		c.Next()
+	}
+}
+
+// CSRFToken 获取当前请求的CSRF令牌，用于服务端渲染的表单
+func CSRFToken(c *gin.Context) string {
+	return c.GetString(CSRFTokenKey)
+}
+
+// csrfSession 当前会话标识，优先使用令牌ID，匿名用户为空
+func csrfSession(c *gin.Context) string {
+	if claims, ok := GetClaims(c); ok && claims.ID != "" {
+		return claims.ID
+	}
+	return c.GetString(AuthUserIdKey)
+}
+
+// newCSRFToken 令牌格式为 base64(随机数|过期时间).base64(签名)
+func newCSRFToken(secret []byte, session string, expiresAt time.Time) string {
+	payload := make([]byte, csrfNonceSize+8)
+	if _, err := rand.Read(payload[:csrfNonceSize]); err != nil {
+		panic(err)
+	}
+	binary.BigEndian.PutUint64(payload[csrfNonceSize:], uint64(expiresAt.Unix()))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signCSRFToken(secret, payload, session))
+}
+
+func verifyCSRFToken(secret []byte, token, session string, now time.Time) bool {
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != csrfNonceSize+8 {
+		return false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, signCSRFToken(secret, payload, session)) {
+		return false
+	}
+	expiresAt := int64(binary.BigEndian.Uint64(payload[csrfNonceSize:]))
+	return now.Unix() < expiresAt
+}
+
+func signCSRFToken(secret, payload []byte, session string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	mac.Write([]byte{0})
+	mac.Write([]byte(session))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,82 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	claim, accessToken := newTestClaim(t)
+
+	r := gin.New()
+	r.Use(
+		OptionalAuth(claim, WithTokenExtractors(CookieTokenExtractor("token"))),
+		CSRF(CSRFOptions{Secret: []byte("secret")}),
+	)
+	r.GET("/form", func(c *gin.Context) {
+		c.String(http.StatusOK, CSRFToken(c))
+	})
+	r.POST("/submit", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	serve := func(method, csrfCookie, csrfHeader string, loggedIn bool) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, map[string]string{http.MethodGet: "/form", http.MethodPost: "/submit"}[method], nil)
+		if csrfCookie != "" {
+			req.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: csrfCookie})
+		}
+		if csrfHeader != "" {
+			req.Header.Set(CSRFHeaderName, csrfHeader)
+		}
+		if loggedIn {
+			req.AddCookie(&http.Cookie{Name: "token", Value: accessToken})
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+	issuedCookie := func(w *httptest.ResponseRecorder) string {
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == CSRFCookieName {
+				assert.False(t, cookie.HttpOnly)
+				return cookie.Value
+			}
+		}
+		return ""
+	}
+
+	// 登录后获取令牌
+	w := serve(http.MethodGet, "", "", true)
+	token := issuedCookie(w)
+	require.NotEmpty(t, token)
+	assert.Equal(t, token, w.Body.String())
+
+	// 令牌有效时不重复下发
+	assert.Empty(t, issuedCookie(serve(http.MethodGet, token, "", true)))
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, token, token, true).Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, token, "", true).Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, token, token+"x", true).Code)
+
+	// 令牌与会话绑定，匿名会话的令牌在登录后失效
+	anonymous := issuedCookie(serve(http.MethodGet, "", "", false))
+	require.NotEmpty(t, anonymous)
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, anonymous, anonymous, false).Code)
+	w = serve(http.MethodPost, anonymous, anonymous, true)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ErrCSRFTokenInvalid.Message)
+	assert.NotEmpty(t, issuedCookie(serve(http.MethodGet, anonymous, "", true)))
+
+	// 使用 Authorization 请求头的请求不校验
+	bearer := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/submit", nil)
+	req.Header.Set(AuthHeaderKey, "Bearer "+accessToken)
+	r.ServeHTTP(bearer, req)
+	assert.Equal(t, http.StatusNoContent, bearer.Code)
+}
@@ -0,0 +1,150 @@
+package xmiddleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 解压请求体失败的错误
+var (
+	ErrContentEncodingUnsupported = xerror.New(xerror.CodeParamError, "unsupported content encoding")
+	ErrContentEncodingInvalid     = xerror.New(xerror.CodeParamError, "invalid compressed request body")
+)
+
+// 压缩比检查的起始大小，避免小请求体因压缩比高被误判
+const decompressRatioCheckSize = 1 << 20
+
+type decompressOptions struct {
+	maxSize  int64
+	maxRatio int64
+}
+
+// DecompressOption 请求体解压配置项
+type DecompressOption func(*decompressOptions)
+
+// WithDecompressMaxSize 解压后请求体的最大字节数，默认10MB
+func WithDecompressMaxSize(maxBytes int64) DecompressOption {
+	return func(o *decompressOptions) {
+		o.maxSize = maxBytes
+	}
+}
+
+// WithDecompressMaxRatio 最大压缩比，解压后超过1MB才检查，默认100
+func WithDecompressMaxRatio(ratio int64) DecompressOption {
+	return func(o *decompressOptions) {
+		o.maxRatio = ratio
+	}
+}
+
+// Decompress 解压 Content-Encoding 为 gzip 或 deflate 的请求体。
+// 解压后大小或压缩比超限时返回413，防止压缩炸弹；不支持的编码返回415
+func Decompress(opts ...DecompressOption) gin.HandlerFunc {
+	o := &decompressOptions{
+		maxSize:  10 << 20,
+		maxRatio: 100,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		compressed := &countingReader{r: c.Request.Body}
+		var (
+			reader io.ReadCloser
+			err    error
+		)
+		switch encoding {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(compressed)
+		case "deflate":
+			reader, err = zlib.NewReader(compressed)
+		default:
+			xhttp.ErrorWithStatus(c, http.StatusUnsupportedMediaType, ErrContentEncodingUnsupported)
+			c.Abort()
+			return
+		}
+		if err != nil {
+			xhttp.ErrorWithStatus(c, http.StatusBadRequest, ErrContentEncodingInvalid)
+			c.Abort()
+			return
+		}
+
+		decompressed := &ratioReader{r: reader, compressed: compressed, maxRatio: o.maxRatio}
+		body := &limitedBody{
+			r:         decompressed,
+			closer:    multiCloser{reader, c.Request.Body},
+			remaining: o.maxSize,
+		}
+		c.Request.Body = body
+		c.Request.ContentLength = -1
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+
+		c.Next()
+
+		abortIfBodyTooLarge(c, body)
+		if !body.exceeded && !c.Writer.Written() && decompressed.err != nil {
+			xhttp.ErrorWithStatus(c, http.StatusBadRequest, ErrContentEncodingInvalid)
+			c.Abort()
+		}
+	}
+}
+
+// countingReader 统计读取的压缩数据字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// ratioReader 解压后大小超过压缩数据的maxRatio倍时返回 xerror.RequestTooLarge
+type ratioReader struct {
+	r            io.Reader
+	compressed   *countingReader
+	maxRatio     int64
+	decompressed int64
+	err          error
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.decompressed += int64(n)
+	if r.maxRatio > 0 && r.decompressed > decompressRatioCheckSize && r.decompressed > r.compressed.n*r.maxRatio {
+		return n, xerror.RequestTooLarge
+	}
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() error {
+	var first error
+	for _, closer := range m {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
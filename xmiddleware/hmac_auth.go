@@ -0,0 +1,145 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RichXan/xcommon/xcache"
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+const hmacNonceKeyPrefix = "hmac:nonce:"
+
+// 签名认证失败的错误，业务码均为 xerror.CodeUnauthorized
+var (
+	ErrSignatureRequired = xerror.New(xerror.CodeUnauthorized, "request signature is required")
+	ErrSignatureInvalid  = xerror.New(xerror.CodeUnauthorized, "invalid request signature")
+	ErrSignatureExpired  = xerror.New(xerror.CodeUnauthorized, "request timestamp is out of range")
+	ErrNonceReused       = xerror.New(xerror.CodeUnauthorized, "request nonce has been used")
+)
+
+// HMACAuthOptions 签名认证中间件配置
+type HMACAuthOptions struct {
+	// 凭证存储，必填
+	Store APIKeyStore
+	// Redis客户端，用于记录已使用的随机数，必填
+	Client *xcache.RedisClient
+	// 允许的时间偏差，默认5分钟
+	MaxSkew time.Duration
+	// 参与签名的请求体最大字节数，默认10MB
+	MaxBodySize int64
+	// 日志，可选
+	Logger *xlog.Logger
+}
+
+// HMACAuth HMAC签名认证中间件，签名方式见 xhttp.CanonicalRequest 和 xhttp.Signer。
+// 时间戳超出允许偏差或随机数重复使用的请求被拒绝，认证成功后设置与 Auth 相同的用户上下文
+func HMACAuth(opts HMACAuthOptions) gin.HandlerFunc {
+	if opts.Store == nil {
+		panic("api key store is nil")
+	}
+	if opts.Client == nil {
+		panic("redis client is nil")
+	}
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = 5 * time.Minute
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 10 << 20
+	}
+	rdb := opts.Client.Client()
+
+	return func(c *gin.Context) {
+		keyID := c.GetHeader(xhttp.SignAccessKeyHeader)
+		timestamp := c.GetHeader(xhttp.SignTimestampHeader)
+		nonce := c.GetHeader(xhttp.SignNonceHeader)
+		signature := c.GetHeader(xhttp.SignatureHeader)
+		if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+			xhttp.Error(c, ErrSignatureRequired)
+			c.Abort()
+			return
+		}
+
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			xhttp.Error(c, ErrSignatureInvalid)
+			c.Abort()
+			return
+		}
+		if skew := time.Since(time.Unix(ts, 0)); skew > opts.MaxSkew || skew < -opts.MaxSkew {
+			xhttp.Error(c, ErrSignatureExpired)
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		apiKey, err := opts.Store.GetAPIKeyByID(ctx, keyID)
+		if err != nil {
+			xhttp.ErrorWithStatus(c, http.StatusInternalServerError, xerror.Wrap(err, xerror.CodeSystemError, "get api key failed"))
+			c.Abort()
+			return
+		}
+		if apiKey == nil || len(apiKey.Secret) == 0 {
+			xhttp.Error(c, ErrSignatureInvalid)
+			c.Abort()
+			return
+		}
+		if e := apiKey.usable(time.Now()); e != nil {
+			xhttp.Error(c, e)
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body, err = io.ReadAll(io.LimitReader(c.Request.Body, opts.MaxBodySize+1))
+			if err != nil {
+				xhttp.ErrorWithStatus(c, http.StatusBadRequest, xerror.Wrap(err, xerror.CodeParamError, "read request body failed"))
+				c.Abort()
+				return
+			}
+			if int64(len(body)) > opts.MaxBodySize {
+				xhttp.Error(c, xerror.RequestTooLarge)
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		canonical := xhttp.CanonicalRequest(c.Request.Method, c.Request.URL.EscapedPath(), c.Request.URL.RawQuery, timestamp, nonce, body)
+		expected := xhttp.ComputeSignature(apiKey.Secret, canonical)
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			xhttp.Error(c, ErrSignatureInvalid)
+			c.Abort()
+			return
+		}
+
+		// 签名校验通过后再记录随机数，避免伪造请求占用随机数；记录时间覆盖整个允许偏差窗口
+		ok, err := rdb.SetNX(ctx, hmacNonceKeyPrefix+keyID+":"+nonce, 1, 2*opts.MaxSkew).Result()
+		if err != nil {
+			// Redis不可用时无法防重放，拒绝请求
+			if opts.Logger != nil {
+				opts.Logger.Error().Err(err).Str("key_id", keyID).Msg("check hmac nonce failed")
+			}
+			xhttp.ErrorWithStatus(c, http.StatusServiceUnavailable, xerror.ServerBusy)
+			c.Abort()
+			return
+		}
+		if !ok {
+			xhttp.Error(c, ErrNonceReused)
+			c.Abort()
+			return
+		}
+
+		setAPIKeyContext(c, apiKey)
+		c.Next()
+	}
+}
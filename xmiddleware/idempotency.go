@@ -0,0 +1,268 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/RichXan/xcommon/xcache"
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// IdempotencyKeyHeader 幂等键请求头
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotencyReplayedHeader 重放响应时设置的响应头
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyKeyPrefix     = "idempotency:"
+	idempotencyLockKeyPrefix = "idempotency:lock:"
+	idempotencyMaxKeyLength  = 255
+)
+
+// 幂等校验失败的错误
+var (
+	ErrIdempotencyKeyRequired = xerror.New(xerror.CodeParamError, "idempotency key is required")
+	ErrIdempotencyKeyInvalid  = xerror.New(xerror.CodeParamError, "idempotency key is invalid")
+	ErrIdempotencyKeyReused   = xerror.New(xerror.CodeRequestRejected, "idempotency key reused with a different request body")
+	ErrIdempotencyInProgress  = xerror.New(xerror.CodeRequestRejected, "a request with the same idempotency key is in progress")
+)
+
+// IdempotencyOptions 幂等中间件配置
+type IdempotencyOptions struct {
+	// Redis客户端，必填
+	Client *xcache.RedisClient
+	// 幂等键请求头，默认 Idempotency-Key
+	Header string
+	// 响应保存时间，默认24小时
+	TTL time.Duration
+	// 处理中的锁过期时间，应大于接口的最长处理时间，默认1分钟
+	LockTTL time.Duration
+	// 需要幂等处理的请求方法，默认 POST 和 PATCH
+	Methods []string
+	// 是否要求必须携带幂等键
+	Required bool
+	// 参与摘要计算的请求体最大字节数，超出时拒绝请求，默认10MB
+	MaxBodySize int64
+	// 日志，可选
+	Logger *xlog.Logger
+}
+
+// idempotencyRecord 保存的首次响应
+type idempotencyRecord struct {
+	BodyHash string      `json:"body_hash"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+}
+
+// Idempotency 幂等中间件，相同用户、路由和幂等键的请求只处理一次，之后重放首次的响应
+func Idempotency(opts IdempotencyOptions) gin.HandlerFunc {
+	if opts.Client == nil {
+		panic("redis client is nil")
+	}
+	if opts.Header == "" {
+		opts.Header = IdempotencyKeyHeader
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	if opts.LockTTL <= 0 {
+		opts.LockTTL = time.Minute
+	}
+	if len(opts.Methods) == 0 {
+		opts.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 10 << 20
+	}
+	methods := make(map[string]struct{}, len(opts.Methods))
+	for _, method := range opts.Methods {
+		methods[method] = struct{}{}
+	}
+	rdb := opts.Client.Client()
+
+	return func(c *gin.Context) {
+		if _, ok := methods[c.Request.Method]; !ok {
+			c.Next()
+			return
+		}
+
+		idempotencyKey := c.GetHeader(opts.Header)
+		if idempotencyKey == "" {
+			if opts.Required {
+				xhttp.ErrorWithStatus(c, http.StatusBadRequest, ErrIdempotencyKeyRequired)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > idempotencyMaxKeyLength {
+			xhttp.ErrorWithStatus(c, http.StatusBadRequest, ErrIdempotencyKeyInvalid)
+			c.Abort()
+			return
+		}
+
+		// 计算请求体摘要，用于识别相同幂等键携带不同请求体的情况
+		var body []byte
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(c.Request.Body, opts.MaxBodySize+1))
+			if err != nil {
+				xhttp.ErrorWithStatus(c, http.StatusBadRequest, xerror.Wrap(err, xerror.CodeParamError, "read request body failed"))
+				c.Abort()
+				return
+			}
+			if int64(len(body)) > opts.MaxBodySize {
+				xhttp.Error(c, xerror.RequestTooLarge)
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+
+		key := idempotencyScope(c) + ":" + idempotencyKey
+		ctx := c.Request.Context()
+
+		if replayIdempotentResponse(c, rdb, key, bodyHash, opts.Logger) {
+			return
+		}
+
+		// 加锁防止相同幂等键的并发请求被重复处理
+		mutex := opts.Client.NewMutex(idempotencyLockKeyPrefix+key, redsync.WithExpiry(opts.LockTTL))
+		if err := mutex.TryLockContext(ctx); err != nil {
+			var taken *redsync.ErrTaken
+			if errors.As(err, &taken) || errors.Is(err, redsync.ErrFailed) {
+				xhttp.ErrorWithStatus(c, http.StatusConflict, ErrIdempotencyInProgress)
+				c.Abort()
+				return
+			}
+			logIdempotencyError(opts.Logger, key, err)
+			c.Next()
+			return
+		}
+		defer func() {
+			if _, err := mutex.Unlock(); err != nil {
+				logIdempotencyError(opts.Logger, key, err)
+			}
+		}()
+
+		// 获取锁期间首个请求可能已完成
+		if replayIdempotentResponse(c, rdb, key, bodyHash, opts.Logger) {
+			return
+		}
+
+		before := headerKeys(c.Writer.Header())
+		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		c.Writer = blw
+
+		c.Next()
+
+		// 服务端错误和业务错误不保存，允许客户端重试。xhttp.Error 的业务错误大多使用200状态码，需要检查错误码
+		status := blw.Status()
+		if status >= http.StatusInternalServerError || c.GetInt(xhttp.ErrorCodeKey) != xerror.CodeSuccess {
+			return
+		}
+
+		record := idempotencyRecord{
+			BodyHash: bodyHash,
+			Status:   status,
+			// 只保存处理函数设置的响应头，请求ID、限流等由其他中间件设置的响应头不重放
+			Header: addedHeaders(before, blw.Header()),
+			Body:   blw.body.Bytes(),
+		}
+		raw, err := json.Marshal(record)
+		if err != nil {
+			logIdempotencyError(opts.Logger, key, err)
+			return
+		}
+		if err := rdb.Set(ctx, idempotencyKeyPrefix+key, raw, opts.TTL).Err(); err != nil {
+			logIdempotencyError(opts.Logger, key, err)
+		}
+	}
+}
+
+// idempotencyScope 幂等键的作用域：用户和路由，未认证的请求按客户端IP区分，避免不同调用方的幂等键互相冲突
+func idempotencyScope(c *gin.Context) string {
+	user := c.GetString(AuthUserIdKey)
+	if user == "" {
+		user = "ip:" + c.ClientIP()
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	return user + ":" + c.Request.Method + ":" + route
+}
+
+// replayIdempotentResponse 存在已保存的响应时重放，返回是否已处理请求
+func replayIdempotentResponse(c *gin.Context, rdb redis.UniversalClient, key, bodyHash string, logger *xlog.Logger) bool {
+	raw, err := rdb.Get(c.Request.Context(), idempotencyKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logIdempotencyError(logger, key, err)
+		}
+		return false
+	}
+	var record idempotencyRecord
+	if err := json.Unmarshal(raw, &record); err != nil {
+		logIdempotencyError(logger, key, err)
+		return false
+	}
+
+	if record.BodyHash != bodyHash {
+		xhttp.ErrorWithStatus(c, http.StatusUnprocessableEntity, ErrIdempotencyKeyReused)
+		c.Abort()
+		return true
+	}
+
+	for k, v := range record.Header {
+		c.Writer.Header()[k] = v
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Status(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+	return true
+}
+
+// headerKeys 记录处理函数执行前已存在的响应头
+func headerKeys(h http.Header) map[string]struct{} {
+	keys := make(map[string]struct{}, len(h))
+	for k := range h {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
+
+// addedHeaders 返回处理函数执行后新增的响应头
+func addedHeaders(before map[string]struct{}, h http.Header) http.Header {
+	added := make(http.Header)
+	for k, v := range h {
+		if _, ok := before[k]; !ok {
+			added[k] = v
+		}
+	}
+	return added
+}
+
+func logIdempotencyError(logger *xlog.Logger, key string, err error) {
+	if logger == nil {
+		return
+	}
+	logger.Error().Str("key", key).Err(err).Msg("idempotency error")
+}
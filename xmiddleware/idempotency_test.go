@@ -0,0 +1,152 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+
+	calls := 0
+	r := gin.New()
+	r.Use(RequestID(), Idempotency(IdempotencyOptions{Client: client}))
+	r.POST("/orders", func(c *gin.Context) {
+		calls++
+		c.Header("Location", "/orders/1")
+		c.JSON(http.StatusCreated, gin.H{"id": calls})
+	})
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("k1", `{"sku":"a"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, 1, calls)
+
+	replay := post("k1", `{"sku":"a"}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, "/orders/1", replay.Header().Get("Location"))
+	assert.Equal(t, "true", replay.Header().Get(IdempotencyReplayedHeader))
+	assert.NotEqual(t, first.Header().Get(RequestIDHeader), replay.Header().Get(RequestIDHeader))
+	assert.Equal(t, 1, calls)
+
+	reused := post("k1", `{"sku":"b"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Contains(t, reused.Body.String(), ErrIdempotencyKeyReused.Message)
+	assert.Equal(t, 1, calls)
+
+	// 不同的幂等键和没有幂等键的请求正常处理
+	assert.Equal(t, http.StatusCreated, post("k2", `{"sku":"a"}`).Code)
+	assert.Equal(t, http.StatusCreated, post("", `{"sku":"a"}`).Code)
+	assert.Equal(t, 3, calls)
+
+	// 未认证时按客户端IP区分，其他调用方使用相同幂等键不会拿到别人的响应
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"sku":"a"}`))
+	req.RemoteAddr = "198.51.100.7:1234"
+	req.Header.Set(IdempotencyKeyHeader, "k1")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, 4, calls)
+}
+
+func TestIdempotencyBodyTooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+
+	r := gin.New()
+	r.Use(Idempotency(IdempotencyOptions{Client: client, MaxBodySize: 8}))
+	r.POST("/orders", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	post := func(body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post(`{"sku":"abc"}`))
+	assert.Equal(t, http.StatusCreated, post(`{}`))
+}
+
+func TestIdempotencyErrorNotSaved(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+
+	calls := 0
+	r := gin.New()
+	r.Use(Idempotency(IdempotencyOptions{Client: client}))
+	r.POST("/orders", func(c *gin.Context) {
+		calls++
+		// 第一次出现临时错误，重试后成功
+		if calls == 1 {
+			xhttp.Error(c, xerror.SystemError)
+			return
+		}
+		xhttp.Success(c, gin.H{"id": calls})
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post()
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Contains(t, first.Body.String(), xerror.SystemError.Message)
+
+	retry := post()
+	assert.Equal(t, 2, calls)
+	assert.Contains(t, retry.Body.String(), `"id":2`)
+	assert.Empty(t, retry.Header().Get(IdempotencyReplayedHeader))
+
+	replay := post()
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, retry.Body.String(), replay.Body.String())
+	assert.Equal(t, "true", replay.Header().Get(IdempotencyReplayedHeader))
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+
+	mutex := client.NewMutex(idempotencyLockKeyPrefix + "ip:192.0.2.1:POST:/orders:k1")
+	assert.NoError(t, mutex.Lock())
+	defer mutex.Unlock()
+
+	r := gin.New()
+	r.Use(Idempotency(IdempotencyOptions{Client: client}))
+	r.POST("/orders", func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "k1")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
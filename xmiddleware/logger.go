@@ -4,28 +4,35 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
 	"github.com/RichXan/xcommon/xlog"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 )
 
 // bodyLogWriter 是一个自定义的响应写入器，用于捕获响应body
 type bodyLogWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
+	// 最多捕获的字节数，0表示不限制
+	limit int
 }
 
 func (w bodyLogWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	w.capture(b)
 	return w.ResponseWriter.Write(b)
 }
 
 // 添加WriteString方法以确保字符串写入也被捕获
 func (w bodyLogWriter) WriteString(s string) (int, error) {
-	w.body.WriteString(s)
+	w.capture([]byte(s))
 	return w.ResponseWriter.WriteString(s)
 }
 
@@ -34,6 +41,29 @@ func (w bodyLogWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+func (w bodyLogWriter) capture(b []byte) {
+	if w.limit <= 0 {
+		w.body.Write(b)
+		return
+	}
+	// 多保留一个字节用于判断是否截断
+	if remaining := w.limit + 1 - w.body.Len(); remaining > 0 {
+		if len(b) > remaining {
+			b = b[:remaining]
+		}
+		w.body.Write(b)
+	}
+}
+
+// isServerErrorCode 是否是服务端原因导致的错误码
+func isServerErrorCode(code int) bool {
+	switch code {
+	case xerror.CodeSystemError, xerror.CodeTimeout, xerror.CodeServerBusy:
+		return true
+	}
+	return false
+}
+
 // formatJSON 格式化JSON字符串
 func formatJSON(data []byte) string {
 	var prettyJSON bytes.Buffer
@@ -43,27 +73,66 @@ func formatJSON(data []byte) string {
 	return prettyJSON.String()
 }
 
+// LoggerOptions 日志中间件配置
+type LoggerOptions struct {
+	// 是否记录JSON请求体和响应体
+	Debug bool
+	// 不记录日志的路径，匹配请求路径或路由模板，如健康检查 /healthz
+	SkipPaths []string
+	// 不记录日志的路径正则
+	SkipPathRegexps []*regexp.Regexp
+	// Debug模式下记录的请求体和响应体最大字节数，默认4KB，超出部分截断
+	MaxBodySize int
+	// 慢请求阈值，超过时按Warn级别记录，默认1秒，小于0表示不检查
+	SlowThreshold time.Duration
+}
+
 // Logger 日志中间件
 func Logger(logger *xlog.Logger, debug bool) gin.HandlerFunc {
+	return LoggerWithOptions(logger, LoggerOptions{Debug: debug})
+}
+
+// LoggerWithOptions 按配置创建日志中间件。
+// 5xx和系统错误码按Error级别记录，4xx、其他错误码和慢请求按Warn级别记录，其余按Info级别记录
+func LoggerWithOptions(logger *xlog.Logger, opts LoggerOptions) gin.HandlerFunc {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 4 << 10
+	}
+	if opts.SlowThreshold == 0 {
+		opts.SlowThreshold = time.Second
+	}
+	skipPaths := make(map[string]struct{}, len(opts.SkipPaths))
+	for _, path := range opts.SkipPaths {
+		skipPaths[path] = struct{}{}
+	}
+
 	return func(c *gin.Context) {
+		if skipLog(c, skipPaths, opts.SkipPathRegexps) {
+			c.Next()
+			return
+		}
+
 		// 开始时间
 		start := time.Now()
 		path := c.Request.URL.Path
 		raw := c.Request.URL.RawQuery
 
-		// 读取请求body
+		// 读取请求body，只读取需要记录的部分，其余内容保留给处理函数
 		var requestBody []byte
-		if debug {
-			if c.Request.Body != nil {
-				requestBody, _ = io.ReadAll(c.Request.Body)
-				// 重新设置请求body，因为读取后需要重置
-				c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		if opts.Debug && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			requestBody, _ = io.ReadAll(io.LimitReader(c.Request.Body, int64(opts.MaxBodySize)+1))
+			c.Request.Body = readCloser{
+				Reader: io.MultiReader(bytes.NewReader(requestBody), c.Request.Body),
+				Closer: c.Request.Body,
 			}
 		}
 
 		// 设置自定义ResponseWriter来捕获响应body
-		blw := &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
-		c.Writer = blw
+		var blw *bodyLogWriter
+		if opts.Debug {
+			blw = &bodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer, limit: opts.MaxBodySize}
+			c.Writer = blw
+		}
 
 		// 处理请求
 		c.Next()
@@ -75,30 +144,83 @@ func Logger(logger *xlog.Logger, debug bool) gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
+		status := c.Writer.Status()
+		// xhttp.Error 的业务错误大多使用200状态码，需要结合错误码判断级别
+		code := c.GetInt(xhttp.ErrorCodeKey)
+		var logEvent *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError, isServerErrorCode(code):
+			logEvent = logger.Error()
+		case status >= http.StatusBadRequest, code != xerror.CodeSuccess, opts.SlowThreshold > 0 && latency >= opts.SlowThreshold:
+			logEvent = logger.Warn()
+		default:
+			logEvent = logger.Info()
+		}
+
 		// 使用结构化日志记录请求信息
-		logEvent := logger.Info().
-			Int("status", c.Writer.Status()).
+		logEvent.
+			Int("status", status).
 			Str("method", c.Request.Method).
 			Str("path", path).
+			Str("route", c.FullPath()).
 			Str("ip", c.ClientIP()).
 			Dur("latency", latency).
 			Str("user_agent", c.Request.UserAgent()).
 			Str("request_id", c.GetString("request_id"))
 
+		if userID := c.GetString(AuthUserIdKey); userID != "" {
+			logEvent.Str("user_id", userID)
+		}
+		if code, ok := c.Get(xhttp.ErrorCodeKey); ok {
+			logEvent.Interface("code", code)
+		}
+		if opts.SlowThreshold > 0 && latency >= opts.SlowThreshold {
+			logEvent.Bool("slow", true)
+		}
+
+		// 请求处理超时
+		if c.GetBool(TimeoutKey) {
+			logEvent.Bool("timeout", true)
+		}
+
 		// 添加请求body（如果存在）
-		if len(requestBody) > 0 && debug {
+		if len(requestBody) > 0 {
 			if strings.Contains(c.Request.Header.Get("Content-Type"), "application/json") {
-				logEvent.Str("request_body", formatJSON(requestBody))
+				logEvent.Str("request_body", formatLogBody(requestBody, opts.MaxBodySize))
 			}
 		}
 
 		// 添加响应body（如果存在）
-		if blw.body.Len() > 0 && debug {
+		if blw != nil && blw.body.Len() > 0 {
 			if strings.Contains(blw.Header().Get("Content-Type"), "application/json") {
-				logEvent.Str("response_body", formatJSON(blw.body.Bytes()))
+				logEvent.Str("response_body", formatLogBody(blw.body.Bytes(), opts.MaxBodySize))
 			}
 		}
 
 		logEvent.Msg("HTTP Request")
 	}
 }
+
+// skipLog 判断请求是否不需要记录日志
+func skipLog(c *gin.Context, skipPaths map[string]struct{}, skipRegexps []*regexp.Regexp) bool {
+	if _, ok := skipPaths[c.Request.URL.Path]; ok {
+		return true
+	}
+	if _, ok := skipPaths[c.FullPath()]; ok {
+		return true
+	}
+	for _, re := range skipRegexps {
+		if re.MatchString(c.Request.URL.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatLogBody 超出最大长度时截断，否则压缩JSON
+func formatLogBody(body []byte, maxSize int) string {
+	if len(body) > maxSize {
+		return string(body[:maxSize]) + "...(truncated)"
+	}
+	return formatJSON(body)
+}
@@ -0,0 +1,92 @@
+package xmiddleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCapturedLogger 创建输出到缓冲区的日志，返回读取日志的函数
+func newCapturedLogger(t *testing.T) (*xlog.Logger, func() []map[string]any) {
+	var buf bytes.Buffer
+	logger := xlog.NewLoggerWithWriter(&buf, zerolog.InfoLevel)
+
+	return logger, func() []map[string]any {
+		var entries []map[string]any
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestLoggerWithOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger, entries := newCapturedLogger(t)
+
+	r := gin.New()
+	r.Use(withUser("u1", nil, nil), LoggerWithOptions(logger, LoggerOptions{
+		Debug:           true,
+		SkipPaths:       []string{"/healthz"},
+		SkipPathRegexps: []*regexp.Regexp{regexp.MustCompile(`^/static/`)},
+		MaxBodySize:     16,
+	}))
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/static/*file", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/users/:id", func(c *gin.Context) {
+		var req map[string]string
+		require.NoError(t, c.ShouldBindJSON(&req))
+		c.JSON(http.StatusOK, req)
+	})
+	r.GET("/missing", func(c *gin.Context) { xhttp.ErrorWithStatus(c, http.StatusNotFound, xerror.GetError) })
+	r.GET("/broken", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	r.GET("/system", func(c *gin.Context) { xhttp.Error(c, xerror.SystemError) })
+	r.GET("/invalid", func(c *gin.Context) { xhttp.Error(c, xerror.ParamError) })
+
+	for _, path := range []string{"/healthz", "/static/app.js", "/missing", "/broken", "/system", "/invalid"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users/1", strings.NewReader(`{"name":"a very long name"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	// 截断只影响日志，处理函数读取到完整请求体
+	assert.JSONEq(t, `{"name":"a very long name"}`, w.Body.String())
+
+	logs := entries()
+	require.Len(t, logs, 5)
+
+	assert.Equal(t, "warn", logs[0]["level"])
+	assert.Equal(t, "/missing", logs[0]["route"])
+	assert.Equal(t, float64(xerror.CodeGetError), logs[0]["code"])
+	assert.Equal(t, "u1", logs[0]["user_id"])
+
+	assert.Equal(t, "error", logs[1]["level"])
+
+	// 200状态码的业务错误按错误码判断级别
+	assert.Equal(t, float64(http.StatusOK), logs[2]["status"])
+	assert.Equal(t, "error", logs[2]["level"])
+	assert.Equal(t, float64(xerror.CodeSystemError), logs[2]["code"])
+	assert.Equal(t, "warn", logs[3]["level"])
+
+	assert.Equal(t, "info", logs[4]["level"])
+	assert.Equal(t, "/users/:id", logs[4]["route"])
+	assert.Equal(t, `{"name":"a very ...(truncated)`, logs[4]["request_body"])
+}
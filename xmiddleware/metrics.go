@@ -0,0 +1,178 @@
+package xmiddleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// unmatchedRoute 未匹配到路由的请求统一使用的route标签，避免标签基数膨胀
+	unmatchedRoute = "unmatched"
+)
+
+// metricsOptions 监控中间件配置
+type metricsOptions struct {
+	namespace       string
+	registerer      prometheus.Registerer
+	durationBuckets []float64
+	sizeBuckets     []float64
+	skipPaths       map[string]struct{}
+}
+
+// MetricsOption 监控中间件配置项
+type MetricsOption func(*metricsOptions)
+
+// WithMetricsNamespace 设置指标的命名空间
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(o *metricsOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithMetricsRegisterer 设置指标注册器，默认使用 prometheus.DefaultRegisterer
+func WithMetricsRegisterer(registerer prometheus.Registerer) MetricsOption {
+	return func(o *metricsOptions) {
+		o.registerer = registerer
+	}
+}
+
+// WithMetricsDurationBuckets 设置请求耗时直方图的分桶（秒）
+func WithMetricsDurationBuckets(buckets []float64) MetricsOption {
+	return func(o *metricsOptions) {
+		o.durationBuckets = buckets
+	}
+}
+
+// WithMetricsSkipPaths 不统计的请求路径，如 /metrics、/health
+func WithMetricsSkipPaths(paths ...string) MetricsOption {
+	return func(o *metricsOptions) {
+		for _, path := range paths {
+			o.skipPaths[path] = struct{}{}
+		}
+	}
+}
+
+// httpMetrics HTTP请求指标
+type httpMetrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// Metrics Prometheus监控中间件，按method、路由模板、状态码和业务错误码统计请求
+func Metrics(opts ...MetricsOption) gin.HandlerFunc {
+	o := &metricsOptions{
+		registerer:      prometheus.DefaultRegisterer,
+		durationBuckets: prometheus.DefBuckets,
+		sizeBuckets:     prometheus.ExponentialBuckets(100, 10, 6),
+		skipPaths:       make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	m := &httpMetrics{
+		requests: registerCollector(o.registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests.",
+		}, []string{"method", "route", "status", "code"})),
+		duration: registerCollector(o.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency in seconds.",
+			Buckets:   o.durationBuckets,
+		}, []string{"method", "route", "status"})),
+		inFlight: registerCollector(o.registerer, prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: o.namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "Number of HTTP requests currently being served.",
+		})),
+		requestSize: registerCollector(o.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: "http",
+			Name:      "request_size_bytes",
+			Help:      "HTTP request body size in bytes.",
+			Buckets:   o.sizeBuckets,
+		}, []string{"method", "route"})),
+		responseSize: registerCollector(o.registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "HTTP response body size in bytes.",
+			Buckets:   o.sizeBuckets,
+		}, []string{"method", "route"})),
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := o.skipPaths[c.Request.URL.Path]; ok {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		method := c.Request.Method
+		status := strconv.Itoa(c.Writer.Status())
+		code := "0"
+		if v, exists := c.Get(xhttp.ErrorCodeKey); exists {
+			if errCode, ok := v.(int); ok {
+				code = strconv.Itoa(errCode)
+			}
+		}
+
+		m.requests.WithLabelValues(method, route, status, code).Inc()
+		m.duration.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		if c.Request.ContentLength > 0 {
+			m.requestSize.WithLabelValues(method, route).Observe(float64(c.Request.ContentLength))
+		}
+		if size := c.Writer.Size(); size > 0 {
+			m.responseSize.WithLabelValues(method, route).Observe(float64(size))
+		}
+	}
+}
+
+// MetricsHandler 暴露指标的处理函数，gatherer为nil时使用 prometheus.DefaultGatherer
+func MetricsHandler(gatherer prometheus.Gatherer) gin.HandlerFunc {
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+	h := promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// registerCollector 注册指标，已注册过同名指标时复用已有的指标
+func registerCollector[T prometheus.Collector](registerer prometheus.Registerer, collector T) T {
+	if err := registerer.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return collector
+}
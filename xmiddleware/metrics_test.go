@@ -0,0 +1,48 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+
+	r := gin.New()
+	r.Use(Metrics(WithMetricsNamespace("test"), WithMetricsRegisterer(registry), WithMetricsSkipPaths("/metrics")))
+	r.GET("/metrics", MetricsHandler(registry))
+	r.GET("/users/:id", func(c *gin.Context) {
+		xhttp.Success(c, c.Param("id"))
+	})
+	r.GET("/forbidden", func(c *gin.Context) {
+		xhttp.Error(c, xerror.Forbidden)
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/forbidden", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	assert.Contains(t, body, `test_http_requests_total{code="0",method="GET",route="/users/:id",status="200"} 2`)
+	assert.Contains(t, body, `test_http_requests_total{code="10002",method="GET",route="/forbidden",status="403"} 1`)
+	assert.Contains(t, body, `test_http_requests_total{code="0",method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `test_http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`)
+	assert.Contains(t, body, `test_http_requests_in_flight 0`)
+	assert.NotContains(t, body, `route="/metrics"`)
+
+	// 重复创建中间件时复用已注册的指标
+	assert.NotPanics(t, func() {
+		Metrics(WithMetricsNamespace("test"), WithMetricsRegisterer(registry))
+	})
+}
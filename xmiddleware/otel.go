@@ -0,0 +1,129 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDKey 链路追踪ID的键名，xhttp响应会读取该值填充trace_id
+	TraceIDKey = "trace_id"
+	// otelInstrumentationName OpenTelemetry埋点名称
+	otelInstrumentationName = "github.com/RichXan/xcommon/xmiddleware"
+)
+
+// otelOptions OpenTelemetry中间件配置
+type otelOptions struct {
+	tracerProvider trace.TracerProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// OtelOption OpenTelemetry中间件配置项
+type OtelOption func(*otelOptions)
+
+// WithTracerProvider 设置TracerProvider，默认使用 otel.GetTracerProvider()
+func WithTracerProvider(provider trace.TracerProvider) OtelOption {
+	return func(o *otelOptions) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithPropagator 设置上下文传播器，默认使用W3C traceparent和baggage
+func WithPropagator(propagator propagation.TextMapPropagator) OtelOption {
+	return func(o *otelOptions) {
+		o.propagator = propagator
+	}
+}
+
+// OtelTracing 基于OpenTelemetry的链路追踪中间件，
+// span写入 c.Request.Context()，trace_id写入gin上下文。
+// 服务名 service.name 是资源属性，需要在创建TracerProvider时通过 sdktrace.WithResource 设置；
+// serviceName 参数只为兼容保留，不再写入span属性
+func OtelTracing(serviceName string, opts ...OtelOption) gin.HandlerFunc {
+	o := &otelOptions{
+		tracerProvider: otel.GetTracerProvider(),
+		propagator:     propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	tracer := o.tracerProvider.Tracer(otelInstrumentationName)
+
+	return func(c *gin.Context) {
+		// 从请求头中提取上游的span上下文和baggage
+		ctx := o.propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		route := c.FullPath()
+		spanName := c.Request.Method
+		if route != "" {
+			spanName = c.Request.Method + " " + route
+		}
+
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.URLPath(c.Request.URL.Path),
+			semconv.ClientAddress(c.ClientIP()),
+			semconv.UserAgentOriginal(c.Request.UserAgent()),
+		}
+		if route != "" {
+			attrs = append(attrs, semconv.HTTPRoute(route))
+		}
+
+		ctx, span := tracer.Start(ctx, spanName,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		// 将span注入到请求上下文
+		c.Request = c.Request.WithContext(ctx)
+		if spanCtx := span.SpanContext(); spanCtx.HasTraceID() {
+			c.Set(TraceIDKey, spanCtx.TraceID().String())
+		}
+
+		// 处理请求
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if requestID := c.GetString(RequestIDKey); requestID != "" {
+			span.SetAttributes(attribute.String("request.id", requestID))
+		}
+		if userID := c.GetString(AuthUserIdKey); userID != "" {
+			span.SetAttributes(attribute.String("enduser.id", userID))
+		}
+		for _, err := range c.Errors {
+			span.RecordError(err.Err)
+		}
+		// xhttp.Error 的业务错误大多使用200状态码，通过错误码识别
+		code := c.GetInt(xhttp.ErrorCodeKey)
+		if code != xerror.CodeSuccess {
+			span.SetAttributes(attribute.Int("error_code", code))
+		}
+		// 服务端span将5xx和业务错误视为错误
+		switch {
+		case status >= http.StatusInternalServerError:
+			span.SetStatus(codes.Error, http.StatusText(status))
+		case len(c.Errors) > 0:
+			span.SetStatus(codes.Error, c.Errors.String())
+		case code != xerror.CodeSuccess:
+			span.SetStatus(codes.Error, "error code "+strconv.Itoa(code))
+		}
+	}
+}
+
+// SpanFromContext 获取当前请求的span
+func SpanFromContext(c *gin.Context) trace.Span {
+	return trace.SpanFromContext(c.Request.Context())
+}
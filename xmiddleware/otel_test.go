@@ -0,0 +1,77 @@
+package xmiddleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOtelTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	r := gin.New()
+	r.Use(OtelTracing("test", WithTracerProvider(provider)))
+	r.GET("/users/:id", func(c *gin.Context) {
+		assert.True(t, trace.SpanFromContext(c.Request.Context()).SpanContext().IsValid())
+		xhttp.Success(c, c.Param("id"))
+	})
+	r.GET("/business", func(c *gin.Context) {
+		xhttp.Error(c, xerror.CreateError)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		_ = c.Error(errors.New("boom"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"trace_id":"`+traceID+`"`)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /users/:id", spans[0].Name)
+	assert.Equal(t, traceID, spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	assert.Contains(t, spans[0].Attributes, attribute.String("http.route", "/users/:id"))
+	assert.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+	for _, attr := range spans[0].Attributes {
+		assert.NotEqual(t, attribute.Key("service.name"), attr.Key)
+	}
+
+	exporter.Reset()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fail", nil))
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	require.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+
+	// 200状态码的业务错误记录错误码
+	exporter.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/business", nil))
+	spans = exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Contains(t, spans[0].Attributes, attribute.Int("error_code", xerror.CodeCreateError))
+	assert.Contains(t, spans[0].Attributes, attribute.Int("http.response.status_code", http.StatusOK))
+}
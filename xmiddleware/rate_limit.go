@@ -0,0 +1,225 @@
+package xmiddleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/RichXan/xcommon/xcache"
+	"github.com/RichXan/xcommon/xconfig"
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	RetryAfterHeader         = "Retry-After"
+	APIKeyHeader             = "X-API-Key"
+)
+
+// RateLimitKeyFunc 从请求中提取限流维度的key，返回空字符串表示不限流
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// RateLimitByIP 按客户端IP限流
+func RateLimitByIP() RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// RateLimitByUser 按Auth中间件写入的用户ID限流，未登录时退化为按IP限流
+func RateLimitByUser() RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		if userID := c.GetString(AuthUserIdKey); userID != "" {
+			return "user:" + userID
+		}
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// RateLimitByAPIKey 按请求头中的API Key限流，header为空时使用X-API-Key。
+// 限流key中保存API Key的SHA-256摘要，避免明文出现在Redis中
+func RateLimitByAPIKey(header string) RateLimitKeyFunc {
+	if header == "" {
+		header = APIKeyHeader
+	}
+	return func(c *gin.Context) string {
+		if apiKey := c.GetHeader(header); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			return "apikey:" + hex.EncodeToString(sum[:])
+		}
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// RateLimitByRoute 按路由模板限流，所有调用方共享同一配额
+func RateLimitByRoute() RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		return "route:" + c.Request.Method + ":" + route
+	}
+}
+
+// RateLimitOptions 限流中间件配置
+type RateLimitOptions struct {
+	// 限流器，必填
+	Limiter RateLimiter
+	// 限流维度，默认按IP
+	KeyFunc RateLimitKeyFunc
+	// 白名单，支持单个IP和CIDR
+	Whitelist []string
+	// 超出限额后的封禁时间，为0时不封禁
+	BanDuration time.Duration
+	// 日志，可选
+	Logger *xlog.Logger
+}
+
+// RateLimit 限流中间件
+func RateLimit(opts RateLimitOptions) gin.HandlerFunc {
+	if opts.Limiter == nil {
+		panic("rate limiter is nil")
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = RateLimitByIP()
+	}
+	whitelist := newIPMatcher(opts.Whitelist)
+
+	return func(c *gin.Context) {
+		if whitelist.match(c.ClientIP()) {
+			c.Next()
+			return
+		}
+
+		key := opts.KeyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		// 检查是否处于封禁期
+		if opts.BanDuration > 0 {
+			banTTL, err := opts.Limiter.BanTTL(ctx, key)
+			if err != nil {
+				// 限流存储异常时放行，避免影响正常业务
+				logRateLimitError(opts.Logger, key, err)
+				c.Next()
+				return
+			}
+			if banTTL > 0 {
+				c.Header(RetryAfterHeader, formatSeconds(banTTL))
+				xhttp.Error(c, xerror.TooManyRequests)
+				c.Abort()
+				return
+			}
+		}
+
+		result, err := opts.Limiter.Allow(ctx, key)
+		if err != nil {
+			logRateLimitError(opts.Logger, key, err)
+			c.Next()
+			return
+		}
+
+		c.Header(RateLimitLimitHeader, strconv.Itoa(result.Limit))
+		c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+		c.Header(RateLimitResetHeader, formatSeconds(result.ResetAfter))
+
+		if !result.Allowed {
+			retryAfter := result.RetryAfter
+			if opts.BanDuration > 0 {
+				if err := opts.Limiter.Ban(ctx, key, opts.BanDuration); err != nil {
+					logRateLimitError(opts.Logger, key, err)
+				} else {
+					retryAfter = opts.BanDuration
+				}
+			}
+			c.Header(RetryAfterHeader, formatSeconds(retryAfter))
+			xhttp.Error(c, xerror.TooManyRequests)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// IPRateLimit 按xconfig.IPLimitConfig对客户端IP做固定窗口限流
+func IPRateLimit(client *xcache.RedisClient, config xconfig.IPLimitConfig) gin.HandlerFunc {
+	return RateLimit(RateLimitOptions{
+		Limiter: NewRedisRateLimiter(client, RateLimitRule{
+			Algorithm: FixedWindow,
+			Limit:     config.MaxRequests,
+			Window:    config.Window,
+		}),
+		KeyFunc:     RateLimitByIP(),
+		Whitelist:   config.Whitelist,
+		BanDuration: config.BanDuration,
+	})
+}
+
+func logRateLimitError(logger *xlog.Logger, key string, err error) {
+	if logger == nil {
+		return
+	}
+	logger.Error().Str("key", key).Err(err).Msg("rate limit error")
+}
+
+// formatSeconds 将时长向上取整为秒
+func formatSeconds(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// ipMatcher 匹配单个IP和CIDR网段
+type ipMatcher struct {
+	ips  map[string]struct{}
+	nets []*net.IPNet
+}
+
+func newIPMatcher(list []string) *ipMatcher {
+	m := &ipMatcher{ips: make(map[string]struct{})}
+	for _, item := range list {
+		if _, ipNet, err := net.ParseCIDR(item); err == nil {
+			m.nets = append(m.nets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(item); ip != nil {
+			m.ips[ip.String()] = struct{}{}
+		}
+	}
+	return m
+}
+
+func (m *ipMatcher) match(addr string) bool {
+	if len(m.ips) == 0 && len(m.nets) == 0 {
+		return false
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	if _, ok := m.ips[ip.String()]; ok {
+		return true
+	}
+	for _, ipNet := range m.nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package xmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xcache"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger() *xlog.Logger {
+	return xlog.NewLoggerWithWriter(os.Stdout, zerolog.ErrorLevel)
+}
+
+func newTestRedisClient(t *testing.T) (*xcache.RedisClient, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client, err := xcache.NewRedisClientByConfig(&xcache.RedisConfig{
+		Addresses: []string{mr.Addr()},
+	}, newTestLogger())
+	require.NoError(t, err)
+	return client, mr
+}
+
+func newRateLimitRouter(opts RateLimitOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RateLimit(opts))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return r
+}
+
+func doRequest(r http.Handler, ip string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = ip + ":12345"
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterAlgorithms(t *testing.T) {
+	client, _ := newTestRedisClient(t)
+	algorithms := map[string]RateLimitAlgorithm{
+		"fixed window":       FixedWindow,
+		"sliding window log": SlidingWindowLog,
+		"token bucket":       TokenBucket,
+	}
+
+	for name, algorithm := range algorithms {
+		rule := RateLimitRule{Algorithm: algorithm, Limit: 3, Window: time.Minute}
+		limiters := map[string]RateLimiter{
+			"memory": NewMemoryRateLimiter(rule),
+			"redis":  NewRedisRateLimiter(client, rule),
+		}
+		for backend, limiter := range limiters {
+			t.Run(backend+" "+name, func(t *testing.T) {
+				ctx := context.Background()
+				key := backend + name
+				for i := 0; i < 3; i++ {
+					result, err := limiter.Allow(ctx, key)
+					require.NoError(t, err)
+					assert.True(t, result.Allowed)
+					assert.Equal(t, 2-i, result.Remaining)
+				}
+				result, err := limiter.Allow(ctx, key)
+				require.NoError(t, err)
+				assert.False(t, result.Allowed)
+				assert.Equal(t, 0, result.Remaining)
+				assert.Greater(t, result.RetryAfter, time.Duration(0))
+			})
+		}
+	}
+}
+
+func TestMemoryRateLimiterTokenBucketRefill(t *testing.T) {
+	limiter := NewMemoryRateLimiter(RateLimitRule{Algorithm: TokenBucket, Limit: 10, Window: 10 * time.Millisecond}).(*MemoryRateLimiter)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 10; i++ {
+		result, err := limiter.Allow(context.Background(), "k")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	// 间隔不足1毫秒的请求同样累积令牌，每毫秒补充1个
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		now = now.Add(600 * time.Microsecond)
+		result, err := limiter.Allow(context.Background(), "k")
+		require.NoError(t, err)
+		if result.Allowed {
+			allowed++
+		}
+	}
+	assert.GreaterOrEqual(t, allowed, 5)
+}
+
+func TestRateLimitByAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.Header.Set(APIKeyHeader, "secret")
+
+	key := RateLimitByAPIKey("")(c)
+	assert.Equal(t, "apikey:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b", key)
+	assert.NotContains(t, key, "secret")
+}
+
+func TestMemoryRateLimiterWindowReset(t *testing.T) {
+	limiter := NewMemoryRateLimiter(RateLimitRule{Algorithm: FixedWindow, Limit: 1, Window: time.Second}).(*MemoryRateLimiter)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	result, err := limiter.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	now = now.Add(time.Second)
+	result, err = limiter.Allow(context.Background(), "k")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	t.Run("Test headers and rejection", func(t *testing.T) {
+		r := newRateLimitRouter(RateLimitOptions{
+			Limiter: NewMemoryRateLimiter(RateLimitRule{Algorithm: FixedWindow, Limit: 1, Window: time.Minute}),
+		})
+
+		w := doRequest(r, "10.0.0.1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Header().Get(RateLimitLimitHeader))
+		assert.Equal(t, "0", w.Header().Get(RateLimitRemainingHeader))
+
+		w = doRequest(r, "10.0.0.1")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get(RetryAfterHeader))
+		assert.Contains(t, w.Body.String(), "too many requests")
+
+		// 其他IP不受影响
+		w = doRequest(r, "10.0.0.2")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Test whitelist", func(t *testing.T) {
+		r := newRateLimitRouter(RateLimitOptions{
+			Limiter:   NewMemoryRateLimiter(RateLimitRule{Algorithm: FixedWindow, Limit: 1, Window: time.Minute}),
+			Whitelist: []string{"192.168.0.0/16"},
+		})
+		for i := 0; i < 3; i++ {
+			w := doRequest(r, "192.168.1.1")
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+	})
+
+	t.Run("Test ban", func(t *testing.T) {
+		client, mr := newTestRedisClient(t)
+		r := newRateLimitRouter(RateLimitOptions{
+			Limiter:     NewRedisRateLimiter(client, RateLimitRule{Algorithm: FixedWindow, Limit: 1, Window: time.Second}),
+			BanDuration: time.Hour,
+		})
+
+		assert.Equal(t, http.StatusOK, doRequest(r, "10.0.0.3").Code)
+		w := doRequest(r, "10.0.0.3")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "3600", w.Header().Get(RetryAfterHeader))
+
+		// 窗口过期后仍处于封禁期
+		mr.FastForward(2 * time.Second)
+		assert.Equal(t, http.StatusTooManyRequests, doRequest(r, "10.0.0.3").Code)
+	})
+}
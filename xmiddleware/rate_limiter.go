@@ -0,0 +1,371 @@
+package xmiddleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/RichXan/xcommon/xcache"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitAlgorithm 限流算法
+type RateLimitAlgorithm int
+
+const (
+	// FixedWindow 固定窗口计数
+	FixedWindow RateLimitAlgorithm = iota
+	// SlidingWindowLog 滑动窗口日志
+	SlidingWindowLog
+	// TokenBucket 令牌桶
+	TokenBucket
+)
+
+const (
+	// rateLimitKeyPrefix 限流计数的key前缀
+	rateLimitKeyPrefix = "ratelimit:"
+	// rateLimitBanKeyPrefix 封禁标记的key前缀
+	rateLimitBanKeyPrefix = "ratelimit:ban:"
+	// memoryRateLimitSweepInterval 内存限流器清理过期数据的间隔
+	memoryRateLimitSweepInterval = time.Minute
+)
+
+// RateLimitRule 限流规则
+type RateLimitRule struct {
+	Algorithm RateLimitAlgorithm
+	// 时间窗口内允许的最大请求数，令牌桶算法下为桶容量
+	Limit int
+	// 时间窗口，令牌桶算法下为填满整个桶所需的时间
+	Window time.Duration
+}
+
+// RateLimitResult 单次限流判断的结果
+type RateLimitResult struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 限额
+	Remaining  int           // 剩余可用次数
+	RetryAfter time.Duration // 被拒绝时建议的重试等待时间
+	ResetAfter time.Duration // 限额完全恢复所需的时间
+}
+
+// RateLimiter 限流器接口
+type RateLimiter interface {
+	// Allow 消耗key的一次请求配额
+	Allow(ctx context.Context, key string) (*RateLimitResult, error)
+	// Ban 封禁key一段时间
+	Ban(ctx context.Context, key string, duration time.Duration) error
+	// BanTTL 返回key剩余的封禁时间，未被封禁时返回0
+	BanTTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+func checkRateLimitRule(rule RateLimitRule) RateLimitRule {
+	if rule.Limit < 1 {
+		rule.Limit = 1
+	}
+	if rule.Window < time.Millisecond {
+		rule.Window = time.Second
+	}
+	return rule
+}
+
+// 固定窗口：INCR计数，首次创建时设置过期时间
+var fixedWindowScript = redis.NewScript(`
+local current = redis.call('INCR', KEYS[1])
+if current == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {current, ttl}
+`)
+
+// 滑动窗口日志：有序集合记录窗口内每次请求的时间戳
+var slidingWindowLogScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return {1, limit - count - 1, 0, window}
+end
+local retry = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+local reset = window
+if newest[2] then
+	reset = tonumber(newest[2]) + window - now
+end
+return {0, 0, retry, reset}
+`)
+
+// 令牌桶：哈希记录剩余令牌数和上次填充时间
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1])
+local ts = tonumber(data[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / rate))
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// RedisRateLimiter 基于Redis的分布式限流器，每种算法都由Lua脚本原子执行
+type RedisRateLimiter struct {
+	client *xcache.RedisClient
+	rule   RateLimitRule
+}
+
+// NewRedisRateLimiter 创建Redis限流器
+func NewRedisRateLimiter(client *xcache.RedisClient, rule RateLimitRule) RateLimiter {
+	return &RedisRateLimiter{
+		client: client,
+		rule:   checkRateLimitRule(rule),
+	}
+}
+
+// Allow 消耗key的一次请求配额
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string) (*RateLimitResult, error) {
+	rdb := l.client.Client()
+	now := time.Now().UnixMilli()
+	window := l.rule.Window.Milliseconds()
+	redisKey := rateLimitKeyPrefix + key
+
+	var (
+		values []int64
+		err    error
+	)
+	switch l.rule.Algorithm {
+	case FixedWindow:
+		values, err = fixedWindowScript.Run(ctx, rdb, []string{redisKey}, window).Int64Slice()
+		if err == nil && len(values) == 2 {
+			current, ttl := values[0], values[1]
+			allowed := int64(0)
+			retry := int64(0)
+			if current <= int64(l.rule.Limit) {
+				allowed = 1
+			} else {
+				retry = ttl
+			}
+			values = []int64{allowed, int64(l.rule.Limit) - current, retry, ttl}
+		}
+	case SlidingWindowLog:
+		member := fmt.Sprintf("%d-%s", now, uuid.New().String())
+		values, err = slidingWindowLogScript.Run(ctx, rdb, []string{redisKey}, now, window, l.rule.Limit, member).Int64Slice()
+	case TokenBucket:
+		rate := float64(l.rule.Limit) / float64(window)
+		values, err = tokenBucketScript.Run(ctx, rdb, []string{redisKey}, l.rule.Limit, rate, now).Int64Slice()
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %d", l.rule.Algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+	return newRateLimitResult(l.rule.Limit, values[0] == 1, values[1], values[2], values[3]), nil
+}
+
+// Ban 封禁key一段时间
+func (l *RedisRateLimiter) Ban(ctx context.Context, key string, duration time.Duration) error {
+	return l.client.Client().Set(ctx, rateLimitBanKeyPrefix+key, "banned", duration).Err()
+}
+
+// BanTTL 返回key剩余的封禁时间
+func (l *RedisRateLimiter) BanTTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := l.client.Client().PTTL(ctx, rateLimitBanKeyPrefix+key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// -2 表示key不存在，-1 表示未设置过期时间
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func newRateLimitResult(limit int, allowed bool, remaining, retryMs, resetMs int64) *RateLimitResult {
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &RateLimitResult{
+		Allowed:    allowed,
+		Limit:      limit,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(retryMs) * time.Millisecond,
+		ResetAfter: time.Duration(resetMs) * time.Millisecond,
+	}
+}
+
+// memoryRateLimitEntry 内存限流器中单个key的状态
+type memoryRateLimitEntry struct {
+	count    int         // 固定窗口计数
+	windowAt time.Time   // 固定窗口开始时间
+	log      []time.Time // 滑动窗口内的请求时间
+	tokens   float64     // 令牌桶剩余令牌
+	filledAt time.Time   // 令牌桶上次填充时间
+	expireAt time.Time   // 状态过期时间
+}
+
+// MemoryRateLimiter 进程内限流器，适用于单实例部署和测试
+type MemoryRateLimiter struct {
+	rule      RateLimitRule
+	mu        sync.Mutex
+	entries   map[string]*memoryRateLimitEntry
+	bans      map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimiter 创建内存限流器
+func NewMemoryRateLimiter(rule RateLimitRule) RateLimiter {
+	return &MemoryRateLimiter{
+		rule:      checkRateLimitRule(rule),
+		entries:   make(map[string]*memoryRateLimitEntry),
+		bans:      make(map[string]time.Time),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow 消耗key的一次请求配额
+func (l *MemoryRateLimiter) Allow(ctx context.Context, key string) (*RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	entry, ok := l.entries[key]
+	if !ok || now.After(entry.expireAt) {
+		entry = &memoryRateLimitEntry{
+			windowAt: now,
+			tokens:   float64(l.rule.Limit),
+			filledAt: now,
+		}
+		l.entries[key] = entry
+	}
+
+	limit := l.rule.Limit
+	window := l.rule.Window
+	switch l.rule.Algorithm {
+	case FixedWindow:
+		if now.Sub(entry.windowAt) >= window {
+			entry.windowAt = now
+			entry.count = 0
+		}
+		entry.count++
+		entry.expireAt = entry.windowAt.Add(window)
+		reset := entry.expireAt.Sub(now)
+		if entry.count <= limit {
+			return newRateLimitResult(limit, true, int64(limit-entry.count), 0, reset.Milliseconds()), nil
+		}
+		return newRateLimitResult(limit, false, 0, reset.Milliseconds(), reset.Milliseconds()), nil
+	case SlidingWindowLog:
+		start := now.Add(-window)
+		kept := entry.log[:0]
+		for _, t := range entry.log {
+			if t.After(start) {
+				kept = append(kept, t)
+			}
+		}
+		entry.log = kept
+		if len(entry.log) < limit {
+			entry.log = append(entry.log, now)
+			entry.expireAt = now.Add(window)
+			return newRateLimitResult(limit, true, int64(limit-len(entry.log)), 0, window.Milliseconds()), nil
+		}
+		retry := entry.log[0].Add(window).Sub(now)
+		reset := entry.log[len(entry.log)-1].Add(window).Sub(now)
+		return newRateLimitResult(limit, false, 0, retry.Milliseconds(), reset.Milliseconds()), nil
+	case TokenBucket:
+		rate := float64(limit) / float64(window.Milliseconds())
+		// 按浮点毫秒计算，间隔不足1毫秒的请求也能累积令牌
+		elapsed := float64(now.Sub(entry.filledAt)) / float64(time.Millisecond)
+		entry.tokens = math.Min(float64(limit), entry.tokens+math.Max(0, elapsed)*rate)
+		entry.filledAt = now
+		entry.expireAt = now.Add(window)
+		allowed := false
+		var retry int64
+		if entry.tokens >= 1 {
+			entry.tokens--
+			allowed = true
+		} else {
+			retry = int64(math.Ceil((1 - entry.tokens) / rate))
+		}
+		reset := int64(math.Ceil((float64(limit) - entry.tokens) / rate))
+		return newRateLimitResult(limit, allowed, int64(entry.tokens), retry, reset), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %d", l.rule.Algorithm)
+	}
+}
+
+// Ban 封禁key一段时间
+func (l *MemoryRateLimiter) Ban(ctx context.Context, key string, duration time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bans[key] = l.now().Add(duration)
+	return nil
+}
+
+// BanTTL 返回key剩余的封禁时间
+func (l *MemoryRateLimiter) BanTTL(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until, ok := l.bans[key]
+	if !ok {
+		return 0, nil
+	}
+	ttl := until.Sub(l.now())
+	if ttl <= 0 {
+		delete(l.bans, key)
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// sweep 定期清理过期的计数和封禁记录，调用方需持有锁
+func (l *MemoryRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < memoryRateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if now.After(entry.expireAt) {
+			delete(l.entries, key)
+		}
+	}
+	for key, until := range l.bans {
+		if now.After(until) {
+			delete(l.bans, key)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package xmiddleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// rbacSubjectKey 当前请求已解析的角色和权限
+	rbacSubjectKey = "rbac_subject"
+	// PermissionWildcard 通配权限，"*" 表示全部权限，"order:*" 表示order下的全部权限
+	PermissionWildcard = "*"
+)
+
+// RoleStore 角色权限存储接口，用于补充JWT中没有携带的角色和权限
+type RoleStore interface {
+	// GetUserRoles 获取用户拥有的角色
+	GetUserRoles(ctx context.Context, userID string) ([]string, error)
+	// GetRolePermissions 获取角色拥有的权限
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+}
+
+// MemoryRoleStore 内存实现的角色权限存储
+type MemoryRoleStore struct {
+	mu              sync.RWMutex
+	userRoles       map[string][]string
+	rolePermissions map[string][]string
+}
+
+// NewMemoryRoleStore 创建内存角色权限存储
+func NewMemoryRoleStore() *MemoryRoleStore {
+	return &MemoryRoleStore{
+		userRoles:       make(map[string][]string),
+		rolePermissions: make(map[string][]string),
+	}
+}
+
+// SetUserRoles 设置用户的角色
+func (s *MemoryRoleStore) SetUserRoles(userID string, roles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.userRoles[userID] = roles
+}
+
+// SetRolePermissions 设置角色的权限
+func (s *MemoryRoleStore) SetRolePermissions(role string, permissions ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rolePermissions[role] = permissions
+}
+
+// GetUserRoles 获取用户拥有的角色
+func (s *MemoryRoleStore) GetUserRoles(ctx context.Context, userID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userRoles[userID], nil
+}
+
+// GetRolePermissions 获取角色拥有的权限
+func (s *MemoryRoleStore) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rolePermissions[role], nil
+}
+
+// OwnerFunc 根据资源ID查询资源所有者的用户ID
+type OwnerFunc func(c *gin.Context, resourceID string) (string, error)
+
+// rbacSubject 当前用户的角色和权限集合
+type rbacSubject struct {
+	roles       map[string]struct{}
+	permissions map[string]struct{}
+}
+
+// Authorizer 基于角色和权限的授权器
+type Authorizer struct {
+	store       RoleStore
+	defaultRole string
+}
+
+// NewAuthorizer 创建授权器，store为nil时只使用JWT中携带的角色和权限，
+// defaultRole为用户没有任何角色时使用的角色，通常取 xconfig.OAuthConfig.DefaultRole
+func NewAuthorizer(store RoleStore, defaultRole string) *Authorizer {
+	return &Authorizer{
+		store:       store,
+		defaultRole: defaultRole,
+	}
+}
+
+var defaultAuthorizer = NewAuthorizer(nil, "")
+
+// RequireRoles 要求当前用户拥有任意一个指定角色，只校验JWT中携带的角色
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return defaultAuthorizer.RequireRoles(roles...)
+}
+
+// RequirePermissions 要求当前用户拥有全部指定权限，只校验JWT中携带的权限
+func RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return defaultAuthorizer.RequirePermissions(permissions...)
+}
+
+// RequireOwner 要求当前用户是资源所有者，拥有bypassRoles中任意角色的用户不受限制
+func RequireOwner(param string, owner OwnerFunc, bypassRoles ...string) gin.HandlerFunc {
+	return defaultAuthorizer.RequireOwner(param, owner, bypassRoles...)
+}
+
+// RequireRoles 要求当前用户拥有任意一个指定角色
+func (a *Authorizer) RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, ok := a.authorize(c)
+		if !ok {
+			return
+		}
+		if len(roles) > 0 && !subject.hasAnyRole(roles) {
+			xhttp.Error(c, xerror.Forbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermissions 要求当前用户拥有全部指定权限
+func (a *Authorizer) RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, ok := a.authorize(c)
+		if !ok {
+			return
+		}
+		for _, permission := range permissions {
+			if !subject.hasPermission(permission) {
+				xhttp.Error(c, xerror.Forbidden)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireOwner 要求当前用户是路径参数param所指资源的所有者，
+// 拥有bypassRoles中任意角色的用户（如管理员）不受限制
+func (a *Authorizer) RequireOwner(param string, owner OwnerFunc, bypassRoles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		subject, ok := a.authorize(c)
+		if !ok {
+			return
+		}
+		if len(bypassRoles) > 0 && subject.hasAnyRole(bypassRoles) {
+			c.Next()
+			return
+		}
+
+		ownerID, err := owner(c, c.Param(param))
+		if err != nil {
+			xhttp.Error(c, err)
+			c.Abort()
+			return
+		}
+		if ownerID == "" || ownerID != c.GetString(AuthUserIdKey) {
+			xhttp.Error(c, xerror.Forbidden)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasRole 判断当前用户是否拥有指定角色，需在授权中间件之后调用
+func HasRole(c *gin.Context, role string) bool {
+	subject, ok := getSubject(c)
+	return ok && subject.hasAnyRole([]string{role})
+}
+
+// HasPermission 判断当前用户是否拥有指定权限，需在授权中间件之后调用
+func HasPermission(c *gin.Context, permission string) bool {
+	subject, ok := getSubject(c)
+	return ok && subject.hasPermission(permission)
+}
+
+// authorize 校验用户已登录并解析其角色和权限，失败时已写入响应
+func (a *Authorizer) authorize(c *gin.Context) (*rbacSubject, bool) {
+	userID := c.GetString(AuthUserIdKey)
+	if userID == "" {
+		xhttp.Error(c, xerror.Unauthorized)
+		c.Abort()
+		return nil, false
+	}
+
+	// 同一授权器在一次请求中只解析一次
+	cacheKey := fmt.Sprintf("%s:%p", rbacSubjectKey, a)
+	if v, exists := c.Get(cacheKey); exists {
+		return v.(*rbacSubject), true
+	}
+
+	subject, err := a.resolve(c, userID)
+	if err != nil {
+		xhttp.Error(c, xerror.Wrap(err, xerror.CodeSystemError, "load roles failed"))
+		c.Abort()
+		return nil, false
+	}
+	c.Set(cacheKey, subject)
+	c.Set(rbacSubjectKey, subject)
+	return subject, true
+}
+
+// resolve 合并JWT和存储中的角色权限
+func (a *Authorizer) resolve(c *gin.Context, userID string) (*rbacSubject, error) {
+	subject := &rbacSubject{
+		roles:       make(map[string]struct{}),
+		permissions: make(map[string]struct{}),
+	}
+	roles := append([]string(nil), c.GetStringSlice(AuthRolesKey)...)
+	if a.store != nil {
+		storeRoles, err := a.store.GetUserRoles(c.Request.Context(), userID)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, storeRoles...)
+	}
+	if len(roles) == 0 && a.defaultRole != "" {
+		roles = []string{a.defaultRole}
+	}
+	for _, role := range roles {
+		subject.roles[role] = struct{}{}
+	}
+
+	for _, permission := range c.GetStringSlice(AuthPermsKey) {
+		subject.permissions[permission] = struct{}{}
+	}
+	if a.store != nil {
+		for role := range subject.roles {
+			permissions, err := a.store.GetRolePermissions(c.Request.Context(), role)
+			if err != nil {
+				return nil, err
+			}
+			for _, permission := range permissions {
+				subject.permissions[permission] = struct{}{}
+			}
+		}
+	}
+	return subject, nil
+}
+
+func getSubject(c *gin.Context) (*rbacSubject, bool) {
+	v, exists := c.Get(rbacSubjectKey)
+	if !exists {
+		return nil, false
+	}
+	subject, ok := v.(*rbacSubject)
+	return subject, ok
+}
+
+func (s *rbacSubject) hasAnyRole(roles []string) bool {
+	for _, role := range roles {
+		if _, ok := s.roles[role]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermission 判断是否拥有权限，支持 "*" 和 "resource:*" 形式的通配
+func (s *rbacSubject) hasPermission(permission string) bool {
+	if _, ok := s.permissions[permission]; ok {
+		return true
+	}
+	if _, ok := s.permissions[PermissionWildcard]; ok {
+		return true
+	}
+	for i := len(permission) - 1; i >= 0; i-- {
+		if permission[i] != ':' {
+			continue
+		}
+		if _, ok := s.permissions[permission[:i+1]+PermissionWildcard]; ok {
+			return true
+		}
+	}
+	return false
+}
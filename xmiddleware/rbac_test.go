@@ -0,0 +1,75 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RichXan/xcommon/xerror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// withUser 模拟Auth中间件写入的用户信息
+func withUser(userID string, roles, permissions []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID != "" {
+			c.Set(AuthUserIdKey, userID)
+			c.Set(AuthUsernameKey, userID)
+			c.Set(AuthRolesKey, roles)
+			c.Set(AuthPermsKey, permissions)
+		}
+		c.Next()
+	}
+}
+
+func serveRBAC(userID string, roles, permissions []string, middleware gin.HandlerFunc) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/orders/:id", withUser(userID, roles, permissions), middleware, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+	return w.Code
+}
+
+func TestRequireRoles(t *testing.T) {
+	assert.Equal(t, http.StatusUnauthorized, serveRBAC("", nil, nil, RequireRoles("admin")))
+	assert.Equal(t, http.StatusForbidden, serveRBAC("u1", []string{"user"}, nil, RequireRoles("admin")))
+	assert.Equal(t, http.StatusOK, serveRBAC("u1", []string{"user", "admin"}, nil, RequireRoles("admin")))
+
+	t.Run("Test default role", func(t *testing.T) {
+		authorizer := NewAuthorizer(nil, "user")
+		assert.Equal(t, http.StatusOK, serveRBAC("u1", nil, nil, authorizer.RequireRoles("user")))
+	})
+}
+
+func TestRequirePermissions(t *testing.T) {
+	assert.Equal(t, http.StatusOK, serveRBAC("u1", nil, []string{"order:read"}, RequirePermissions("order:read")))
+	assert.Equal(t, http.StatusForbidden, serveRBAC("u1", nil, []string{"order:read"}, RequirePermissions("order:read", "order:write")))
+	assert.Equal(t, http.StatusOK, serveRBAC("u1", nil, []string{"order:*"}, RequirePermissions("order:write")))
+	assert.Equal(t, http.StatusOK, serveRBAC("u1", nil, []string{"*"}, RequirePermissions("user:delete")))
+
+	t.Run("Test role store", func(t *testing.T) {
+		store := NewMemoryRoleStore()
+		store.SetUserRoles("u1", "editor")
+		store.SetRolePermissions("editor", "article:write")
+		authorizer := NewAuthorizer(store, "")
+		assert.Equal(t, http.StatusOK, serveRBAC("u1", nil, nil, authorizer.RequirePermissions("article:write")))
+		assert.Equal(t, http.StatusForbidden, serveRBAC("u2", nil, nil, authorizer.RequirePermissions("article:write")))
+	})
+}
+
+func TestRequireOwner(t *testing.T) {
+	owner := func(c *gin.Context, resourceID string) (string, error) {
+		if resourceID != "1" {
+			return "", xerror.GetError
+		}
+		return "u1", nil
+	}
+	assert.Equal(t, http.StatusOK, serveRBAC("u1", nil, nil, RequireOwner("id", owner)))
+	assert.Equal(t, http.StatusForbidden, serveRBAC("u2", nil, nil, RequireOwner("id", owner)))
+	assert.Equal(t, http.StatusOK, serveRBAC("u2", []string{"admin"}, nil, RequireOwner("id", owner, "admin")))
+}
@@ -0,0 +1,211 @@
+package xmiddleware
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+	"github.com/RichXan/xcommon/xutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultPanicDedupInterval 相同panic的默认通知间隔
+const defaultPanicDedupInterval = 10 * time.Minute
+
+// PanicEvent panic事件详情
+type PanicEvent struct {
+	Time      time.Time
+	Error     interface{}
+	Stack     string
+	Method    string
+	Path      string
+	Route     string
+	ClientIP  string
+	RequestID string
+	UserID    string
+}
+
+// PanicNotifier panic告警通知
+type PanicNotifier func(event *PanicEvent)
+
+// recoveryOptions 恢复中间件配置
+type recoveryOptions struct {
+	notifier      PanicNotifier
+	dedupInterval time.Duration
+}
+
+// RecoveryOption 恢复中间件配置项
+type RecoveryOption func(*recoveryOptions)
+
+// WithPanicNotifier 设置panic告警通知，通知在独立的goroutine中执行
+func WithPanicNotifier(notifier PanicNotifier) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.notifier = notifier
+	}
+}
+
+// WithPanicDedupInterval 设置相同panic（相同错误和路由）的通知间隔，间隔内只通知一次
+func WithPanicDedupInterval(interval time.Duration) RecoveryOption {
+	return func(o *recoveryOptions) {
+		o.dedupInterval = interval
+	}
+}
+
+// SMTPPanicNotifier 通过邮件发送panic告警，收件人使用SMTPClient的默认配置
+func SMTPPanicNotifier(client *xutil.SMTPClient, subject string) PanicNotifier {
+	return func(event *PanicEvent) {
+		body := fmt.Sprintf("time: %s\nmethod: %s\npath: %s\nroute: %s\nclient_ip: %s\nrequest_id: %s\nuser_id: %s\nerror: %v\n\n%s",
+			event.Time.Format(time.RFC3339), event.Method, event.Path, event.Route, event.ClientIP,
+			event.RequestID, event.UserID, event.Error, event.Stack)
+		_ = client.SendEmail(xutil.EmailParams{
+			Subject:  fmt.Sprintf("%s: %v", subject, event.Error),
+			Body:     body,
+			BodyType: xutil.PLAIN,
+		})
+	}
+}
+
+// Recovery 恢复中间件，捕获panic并记录日志，返回 xerror.SystemError
+func Recovery(logger *xlog.Logger, opts ...RecoveryOption) gin.HandlerFunc {
+	o := &recoveryOptions{dedupInterval: defaultPanicDedupInterval}
+	for _, opt := range opts {
+		opt(o)
+	}
+	dedup := newPanicDeduplicator(o.dedupInterval)
+
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			stack := debug.Stack()
+			// 超时中间件在独立goroutine中执行处理函数，使用其记录的原始堆栈
+			if p, ok := recovered.(*handlerPanic); ok {
+				recovered, stack = p.value, p.stack
+			}
+			// http.ErrAbortHandler 用于主动中断响应，按标准库约定继续向上抛出
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			event := &PanicEvent{
+				Time:      time.Now(),
+				Error:     recovered,
+				Stack:     string(stack),
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				Route:     c.FullPath(),
+				ClientIP:  c.ClientIP(),
+				RequestID: c.GetString(RequestIDKey),
+				UserID:    c.GetString(AuthUserIdKey),
+			}
+
+			// 客户端已断开连接，无法再写入响应
+			if isBrokenPipe(recovered) {
+				logger.Error().
+					Any("error", recovered).
+					Str("method", event.Method).
+					Str("path", event.Path).
+					Str("request_id", event.RequestID).
+					Msg("connection broken")
+				if err, ok := recovered.(error); ok {
+					_ = c.Error(err)
+				}
+				c.Abort()
+				return
+			}
+
+			logger.Error().
+				Any("error", recovered).
+				Str("method", event.Method).
+				Str("path", event.Path).
+				Str("route", event.Route).
+				Str("ip", event.ClientIP).
+				Str("request_id", event.RequestID).
+				Str("user_id", event.UserID).
+				Str("stack", event.Stack).
+				Msg("panic recovered")
+
+			if o.notifier != nil && dedup.allow(fmt.Sprintf("%v|%s %s", recovered, event.Method, event.Route)) {
+				go func() {
+					defer func() {
+						if err := recover(); err != nil {
+							logger.Error().Any("error", err).Msg("panic notifier failed")
+						}
+					}()
+					o.notifier(event)
+				}()
+			}
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			xhttp.ErrorWithStatus(c, http.StatusInternalServerError, xerror.SystemError)
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
+
+// isBrokenPipe 判断是否为客户端断开连接导致的写入错误
+func isBrokenPipe(recovered interface{}) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var ne *net.OpError
+	if !errors.As(err, &ne) {
+		return false
+	}
+	var se *os.SyscallError
+	if !errors.As(ne, &se) {
+		return false
+	}
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
+
+// panicDeduplicator 在间隔内对相同的panic只放行一次通知
+type panicDeduplicator struct {
+	interval time.Duration
+	mu       sync.Mutex
+	sent     map[string]time.Time
+}
+
+func newPanicDeduplicator(interval time.Duration) *panicDeduplicator {
+	return &panicDeduplicator{
+		interval: interval,
+		sent:     make(map[string]time.Time),
+	}
+}
+
+func (d *panicDeduplicator) allow(key string) bool {
+	if d.interval <= 0 {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	if last, ok := d.sent[key]; ok && now.Sub(last) < d.interval {
+		return false
+	}
+	for k, last := range d.sent {
+		if now.Sub(last) >= d.interval {
+			delete(d.sent, k)
+		}
+	}
+	d.sent[key] = now
+	return true
+}
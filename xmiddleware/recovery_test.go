@@ -0,0 +1,50 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	notified := make(chan *PanicEvent, 4)
+
+	r := gin.New()
+	r.Use(RequestID(), Recovery(newTestLogger(), WithPanicNotifier(func(event *PanicEvent) {
+		notified <- event
+	})))
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), xerror.SystemError.Message)
+	}
+
+	select {
+	case event := <-notified:
+		assert.Equal(t, "boom", event.Error)
+		assert.Equal(t, "/panic", event.Route)
+		assert.NotEmpty(t, event.RequestID)
+		assert.NotEmpty(t, event.Stack)
+	case <-time.After(time.Second):
+		t.Fatal("notifier not called")
+	}
+
+	// 相同的panic在间隔内只通知一次
+	select {
+	case <-notified:
+		t.Fatal("duplicate panic notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -1,32 +1,67 @@
 package xmiddleware
 
 import (
+	"github.com/RichXan/xcommon/xutil"
+
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 const (
 	// RequestIDKey 请求ID的键名
 	RequestIDKey = "request_id"
 	// RequestIDHeader 请求ID的请求头
-	RequestIDHeader = "X-Request-ID"
+	RequestIDHeader = xutil.RequestIDHeader
 )
 
-// RequestID 请求ID中间件
-func RequestID() gin.HandlerFunc {
+type requestIDOptions struct {
+	generator xutil.RequestIDGenerator
+	validator func(string) bool
+}
+
+// RequestIDOption 请求ID中间件配置项
+type RequestIDOption func(*requestIDOptions)
+
+// WithRequestIDGenerator 设置请求ID生成器，默认 xutil.UUIDv4，
+// 可选 xutil.UUIDv7、xutil.ULID 或 xutil.NewSnowflakeGenerator
+func WithRequestIDGenerator(generator xutil.RequestIDGenerator) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.generator = generator
+	}
+}
+
+// WithRequestIDValidator 设置请求头中请求ID的校验函数，默认 xutil.ValidRequestID；
+// 传入nil表示不信任请求头，始终生成新的请求ID
+func WithRequestIDValidator(validator func(string) bool) RequestIDOption {
+	return func(o *requestIDOptions) {
+		o.validator = validator
+	}
+}
+
+// RequestID 请求ID中间件，请求头中的请求ID校验失败时重新生成。
+// 请求ID同时保存到 c.Request.Context()，可通过 xutil.RequestIDFromContext 获取并传递给下游
+func RequestID(opts ...RequestIDOption) gin.HandlerFunc {
+	o := &requestIDOptions{
+		generator: xutil.UUIDv4,
+		validator: xutil.ValidRequestID,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return func(c *gin.Context) {
 		// 优先从请求头获取
 		requestID := c.GetHeader(RequestIDHeader)
-		if requestID == "" {
-			// 如果请求头中没有，则生成新的
-			requestID = uuid.New().String()
+		if requestID == "" || o.validator == nil || !o.validator(requestID) {
+			// 如果请求头中没有或格式不合法，则生成新的
+			requestID = o.generator()
 		}
 
 		// 设置到上下文
 		c.Set(RequestIDKey, requestID)
+		c.Request = c.Request.WithContext(xutil.WithRequestID(c.Request.Context(), requestID))
 		// 设置响应头
 		c.Writer.Header().Set(RequestIDHeader, requestID)
 
 		c.Next()
 	}
-} 
\ No newline at end of file
+}
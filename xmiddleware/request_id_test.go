@@ -0,0 +1,70 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RichXan/xcommon/xutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	snowflake, err := xutil.NewSnowflakeGenerator(1)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		generator xutil.RequestIDGenerator
+		incoming  string
+		check     func(t *testing.T, id string)
+	}{
+		{name: "trusted header", incoming: "req-123:abc", check: func(t *testing.T, id string) {
+			assert.Equal(t, "req-123:abc", id)
+		}},
+		{name: "invalid header", incoming: "bad id\r\nx", check: func(t *testing.T, id string) {
+			assert.Len(t, id, 36)
+		}},
+		{name: "too long", incoming: strings.Repeat("a", 65), check: func(t *testing.T, id string) {
+			assert.Len(t, id, 36)
+		}},
+		{name: "uuidv7", generator: xutil.UUIDv7, check: func(t *testing.T, id string) {
+			assert.Equal(t, byte('7'), id[14])
+		}},
+		{name: "ulid", generator: xutil.ULID, check: func(t *testing.T, id string) {
+			assert.Len(t, id, 26)
+			assert.True(t, xutil.ValidRequestID(id))
+		}},
+		{name: "snowflake", generator: snowflake, check: func(t *testing.T, id string) {
+			assert.NotEqual(t, id, snowflake())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []RequestIDOption
+			if tt.generator != nil {
+				opts = append(opts, WithRequestIDGenerator(tt.generator))
+			}
+			r := gin.New()
+			r.Use(RequestID(opts...))
+			r.GET("/", func(c *gin.Context) {
+				// 请求ID同时保存在 gin.Context 和 context.Context 中
+				assert.Equal(t, c.GetString(RequestIDKey), xutil.RequestIDFromContext(c.Request.Context()))
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			r.ServeHTTP(w, req)
+			tt.check(t, w.Header().Get(RequestIDHeader))
+		})
+	}
+}
@@ -0,0 +1,467 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RichXan/xcommon/xcache"
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+	"github.com/RichXan/xcommon/xlog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// CacheStatusHeader 响应缓存命中情况，HIT 或 MISS
+	CacheStatusHeader = "X-Cache"
+
+	responseCacheKeyPrefix  = "respcache:"
+	responseCacheTagPrefix  = "respcache:tag:"
+	responseCacheLockPrefix = "respcache:lock:"
+)
+
+// cachedResponse 缓存的响应
+type cachedResponse struct {
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+	ETag         string      `json:"etag"`
+	LastModified int64       `json:"last_modified"`
+}
+
+// ResponseCache 基于Redis的GET接口响应缓存
+type ResponseCache struct {
+	client   *xcache.RedisClient
+	logger   *xlog.Logger
+	lockTTL  time.Duration
+	lockWait time.Duration
+}
+
+// NewResponseCache 创建响应缓存，logger可为nil
+func NewResponseCache(client *xcache.RedisClient, logger *xlog.Logger) *ResponseCache {
+	if client == nil {
+		panic("redis client is nil")
+	}
+	return &ResponseCache{
+		client:   client,
+		logger:   logger,
+		lockTTL:  10 * time.Second,
+		lockWait: 3 * time.Second,
+	}
+}
+
+// cacheRule 单个路由的缓存规则
+type cacheRule struct {
+	perUser bool
+	tags    []string
+	tagFunc func(c *gin.Context) []string
+}
+
+// CacheRuleOption 路由缓存规则配置项
+type CacheRuleOption func(*cacheRule)
+
+// WithCachePerUser 按用户区分缓存，用于返回用户私有数据的接口
+func WithCachePerUser() CacheRuleOption {
+	return func(r *cacheRule) {
+		r.perUser = true
+	}
+}
+
+// WithCacheTags 为缓存打上标签，写接口可以按标签清除缓存
+func WithCacheTags(tags ...string) CacheRuleOption {
+	return func(r *cacheRule) {
+		r.tags = append(r.tags, tags...)
+	}
+}
+
+// WithCacheTagFunc 根据请求动态生成缓存标签，如 "order:{id}"
+func WithCacheTagFunc(fn func(c *gin.Context) []string) CacheRuleOption {
+	return func(r *cacheRule) {
+		r.tagFunc = fn
+	}
+}
+
+// Cache 缓存GET和HEAD请求的响应，ttl为缓存时间，处理函数返回的 Cache-Control: max-age 更短时使用max-age。
+// 请求头 Cache-Control: no-store 跳过缓存，no-cache 跳过读取并刷新缓存；
+// 处理函数返回 Cache-Control: no-store/private/max-age=0、非200状态码或 xhttp.Error 业务错误时不缓存
+func (rc *ResponseCache) Cache(ttl time.Duration, opts ...CacheRuleOption) gin.HandlerFunc {
+	rule := &cacheRule{}
+	for _, opt := range opts {
+		opt(rule)
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		requestCacheControl := parseCacheControl(c.GetHeader("Cache-Control"))
+		if _, ok := requestCacheControl["no-store"]; ok {
+			c.Next()
+			return
+		}
+		if rule.perUser && c.GetString(AuthUserIdKey) == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		key := rc.cacheKey(c, rule.perUser)
+		_, noCache := requestCacheControl["no-cache"]
+
+		if !noCache {
+			if entry := rc.load(ctx, key); entry != nil {
+				rc.serve(c, entry, "HIT")
+				return
+			}
+
+			// 防止缓存击穿：只允许一个请求回源，其余请求等待缓存写入。
+			// 锁带有持有者标识，回源超过lockTTL后不会误删其他请求持有的锁
+			lock, err := rc.client.TryLock(ctx, responseCacheLockPrefix+key, rc.lockTTL)
+			switch {
+			case errors.Is(err, xcache.ErrLockNotAcquired):
+				if entry := rc.wait(ctx, key); entry != nil {
+					rc.serve(c, entry, "HIT")
+					return
+				}
+			case err != nil:
+				rc.logError(key, err)
+			default:
+				defer func() {
+					if err := lock.Unlock(context.WithoutCancel(ctx)); err != nil && !errors.Is(err, xcache.ErrLockNotHeld) {
+						rc.logError(key, err)
+					}
+				}()
+			}
+		}
+
+		before := headerKeys(c.Writer.Header())
+		original := c.Writer
+		cw := &cacheWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = cw
+
+		c.Next()
+
+		c.Writer = original
+		entry := &cachedResponse{
+			Status:       cw.status,
+			Header:       addedHeaders(before, original.Header()),
+			Body:         cw.body.Bytes(),
+			ETag:         computeETag(cw.body.Bytes()),
+			LastModified: time.Now().Unix(),
+		}
+		// xhttp.Error 的业务错误大多使用200状态码，有错误码时不缓存
+		if c.GetInt(xhttp.ErrorCodeKey) == xerror.CodeSuccess && rc.storable(entry, rule.perUser) {
+			if entryTTL := responseTTL(ttl, entry.Header); entryTTL > 0 {
+				tags := append([]string(nil), rule.tags...)
+				if rule.tagFunc != nil {
+					tags = append(tags, rule.tagFunc(c)...)
+				}
+				rc.store(ctx, key, entry, entryTTL, tags)
+			}
+			rc.serve(c, entry, "MISS")
+			return
+		}
+
+		// 不缓存时原样写出响应
+		original.WriteHeader(cw.status)
+		if cw.body.Len() > 0 {
+			_, _ = original.Write(cw.body.Bytes())
+		} else if cw.written {
+			original.WriteHeaderNow()
+		}
+	}
+}
+
+// InvalidateTags 写接口的中间件，处理成功（状态码小于400）后清除指定标签下的缓存
+func (rc *ResponseCache) InvalidateTags(tags ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		if err := rc.Invalidate(c.Request.Context(), tags...); err != nil {
+			rc.logError(strings.Join(tags, ","), err)
+		}
+	}
+}
+
+// Invalidate 清除指定标签下的全部缓存
+func (rc *ResponseCache) Invalidate(ctx context.Context, tags ...string) error {
+	rdb := rc.client.Client()
+	for _, tag := range tags {
+		tagKey := responseCacheTagPrefix + tag
+		keys, err := rdb.SMembers(ctx, tagKey).Result()
+		if err != nil {
+			return err
+		}
+		// 逐个删除，兼容集群模式下key不在同一个slot的情况
+		for _, key := range keys {
+			if err := rdb.Del(ctx, key).Err(); err != nil {
+				return err
+			}
+		}
+		if err := rdb.Del(ctx, tagKey).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cacheKey 由请求方法、路由模板、规范化的查询参数和可选的用户ID组成，
+// HEAD请求的响应没有响应体，不能与GET共用缓存
+func (rc *ResponseCache) cacheKey(c *gin.Context, perUser bool) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	var b strings.Builder
+	b.WriteString(c.Request.Method)
+	b.WriteByte(' ')
+	b.WriteString(c.Request.URL.Path)
+	b.WriteByte('?')
+	b.WriteString(normalizeQuery(c.Request.URL.Query()))
+	if perUser {
+		b.WriteString("|user:")
+		b.WriteString(c.GetString(AuthUserIdKey))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return responseCacheKeyPrefix + route + ":" + hex.EncodeToString(sum[:16])
+}
+
+func (rc *ResponseCache) load(ctx context.Context, key string) *cachedResponse {
+	raw, err := rc.client.Client().Get(ctx, key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			rc.logError(key, err)
+		}
+		return nil
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		rc.logError(key, err)
+		return nil
+	}
+	return &entry
+}
+
+// wait 等待回源的请求写入缓存
+func (rc *ResponseCache) wait(ctx context.Context, key string) *cachedResponse {
+	deadline := time.Now().Add(rc.lockWait)
+	interval := 20 * time.Millisecond
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+		if entry := rc.load(ctx, key); entry != nil {
+			return entry
+		}
+		// 回源请求已结束但没有写入缓存（如返回错误），不再等待
+		if n, err := rc.client.Client().Exists(ctx, responseCacheLockPrefix+key).Result(); err != nil || n == 0 {
+			return nil
+		}
+		if interval < 200*time.Millisecond {
+			interval *= 2
+		}
+	}
+	return nil
+}
+
+func (rc *ResponseCache) storable(entry *cachedResponse, perUser bool) bool {
+	if entry.Status != http.StatusOK {
+		return false
+	}
+	if len(entry.Header.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	cacheControl := parseCacheControl(entry.Header.Get("Cache-Control"))
+	if _, ok := cacheControl["no-store"]; ok {
+		return false
+	}
+	if _, ok := cacheControl["private"]; ok && !perUser {
+		return false
+	}
+	return true
+}
+
+// responseTTL 返回配置的缓存时间和响应 Cache-Control 中 s-maxage 或 max-age 的较小值
+func responseTTL(ttl time.Duration, header http.Header) time.Duration {
+	cacheControl := parseCacheControl(header.Get("Cache-Control"))
+	maxAge, ok := cacheControl["s-maxage"]
+	if !ok {
+		maxAge, ok = cacheControl["max-age"]
+	}
+	if !ok {
+		return ttl
+	}
+	seconds, err := strconv.Atoi(maxAge)
+	if err != nil {
+		return ttl
+	}
+	return min(ttl, time.Duration(seconds)*time.Second)
+}
+
+func (rc *ResponseCache) store(ctx context.Context, key string, entry *cachedResponse, ttl time.Duration, tags []string) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		rc.logError(key, err)
+		return
+	}
+	rdb := rc.client.Client()
+	if err := rdb.Set(ctx, key, raw, ttl).Err(); err != nil {
+		rc.logError(key, err)
+		return
+	}
+	for _, tag := range tags {
+		tagKey := responseCacheTagPrefix + tag
+		if err := rdb.SAdd(ctx, tagKey, key).Err(); err != nil {
+			rc.logError(key, err)
+			continue
+		}
+		// 标签集合的过期时间不短于其中缓存的过期时间
+		if current, err := rdb.TTL(ctx, tagKey).Result(); err == nil && current < ttl {
+			rdb.Expire(ctx, tagKey, ttl)
+		}
+	}
+}
+
+// serve 输出缓存的响应，支持 If-None-Match 和 If-Modified-Since 条件请求
+func (rc *ResponseCache) serve(c *gin.Context, entry *cachedResponse, status string) {
+	for k, v := range entry.Header {
+		c.Writer.Header()[k] = v
+	}
+	lastModified := time.Unix(entry.LastModified, 0).UTC()
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header(CacheStatusHeader, status)
+
+	if notModified(c.Request, entry.ETag, lastModified) {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Length")
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		c.Abort()
+		return
+	}
+
+	c.Status(entry.Status)
+	if c.Request.Method != http.MethodHead {
+		_, _ = c.Writer.Write(entry.Body)
+	} else {
+		c.Writer.WriteHeaderNow()
+	}
+	c.Abort()
+}
+
+func (rc *ResponseCache) logError(key string, err error) {
+	if rc.logger == nil {
+		return
+	}
+	rc.logger.Error().Str("key", key).Err(err).Msg("response cache error")
+}
+
+// notModified 判断条件请求是否可以返回304
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !lastModified.After(t)
+		}
+	}
+	return false
+}
+
+// computeETag 根据响应体生成强校验ETag
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// normalizeQuery 按参数名和参数值排序，保证参数顺序不同的请求命中同一缓存
+func normalizeQuery(query url.Values) string {
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	return query.Encode()
+}
+
+// parseCacheControl 解析 Cache-Control 指令
+func parseCacheControl(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, arg, _ := strings.Cut(part, "=")
+		directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(arg), `"`)
+	}
+	return directives
+}
+
+// cacheWriter 缓冲处理函数的响应，处理完成后再决定是否缓存以及是否返回304
+type cacheWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if !w.written {
+		w.status = code
+	}
+}
+
+func (w *cacheWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *cacheWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *cacheWriter) Status() int {
+	return w.status
+}
+
+func (w *cacheWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *cacheWriter) Written() bool {
+	return w.written
+}
+
+// Flush 响应在处理完成前被缓冲，不支持提前刷新
+func (w *cacheWriter) Flush() {}
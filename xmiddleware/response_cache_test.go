@@ -0,0 +1,223 @@
+package xmiddleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+	rc := NewResponseCache(client, nil)
+
+	calls := 0
+	r := gin.New()
+	r.GET("/orders", rc.Cache(time.Minute, WithCacheTags("orders")), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusOK, gin.H{"calls": calls})
+	})
+	r.GET("/fail", rc.Cache(time.Minute), func(c *gin.Context) {
+		calls++
+		c.String(http.StatusNotFound, "not found")
+	})
+	r.POST("/orders", rc.InvalidateTags("orders"), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("/orders?b=2&a=1", nil)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "MISS", first.Header().Get(CacheStatusHeader))
+	assert.NotEmpty(t, first.Header().Get("ETag"))
+
+	// 参数顺序不同也命中缓存
+	hit := get("/orders?a=1&b=2", nil)
+	assert.Equal(t, "HIT", hit.Header().Get(CacheStatusHeader))
+	assert.Equal(t, first.Body.String(), hit.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", hit.Header().Get("Content-Type"))
+	assert.Equal(t, 1, calls)
+
+	notModified := get("/orders?a=1&b=2", map[string]string{"If-None-Match": first.Header().Get("ETag")})
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+	assert.Empty(t, notModified.Body.String())
+
+	// no-cache 跳过读取并刷新缓存
+	refreshed := get("/orders?a=1&b=2", map[string]string{"Cache-Control": "no-cache"})
+	assert.Equal(t, "MISS", refreshed.Header().Get(CacheStatusHeader))
+	assert.Equal(t, 2, calls)
+
+	// 写接口按标签清除缓存
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/orders", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "MISS", get("/orders?a=1&b=2", nil).Header().Get(CacheStatusHeader))
+	assert.Equal(t, 3, calls)
+
+	// 非200响应原样返回且不缓存
+	for i := 0; i < 2; i++ {
+		fail := get("/fail", nil)
+		assert.Equal(t, http.StatusNotFound, fail.Code)
+		assert.Equal(t, "not found", fail.Body.String())
+		assert.Empty(t, fail.Header().Get(CacheStatusHeader))
+	}
+	assert.Equal(t, 5, calls)
+}
+
+func TestResponseCacheMethodAndMaxAge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, mr := newTestRedisClient(t)
+	rc := NewResponseCache(client, nil)
+
+	calls := 0
+	r := gin.New()
+	r.HEAD("/items", rc.Cache(time.Minute), func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusOK)
+	})
+	r.GET("/items", rc.Cache(time.Minute), func(c *gin.Context) {
+		calls++
+		c.Header("Cache-Control", "public, max-age=5")
+		c.String(http.StatusOK, "items")
+	})
+	r.GET("/live", rc.Cache(time.Minute), func(c *gin.Context) {
+		calls++
+		c.Header("Cache-Control", "max-age=0")
+		c.String(http.StatusOK, "live")
+	})
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	// HEAD和GET不共用缓存
+	assert.Equal(t, "MISS", do(http.MethodHead, "/items").Header().Get(CacheStatusHeader))
+	get := do(http.MethodGet, "/items")
+	assert.Equal(t, "MISS", get.Header().Get(CacheStatusHeader))
+	assert.Equal(t, "items", get.Body.String())
+	assert.Equal(t, "HIT", do(http.MethodGet, "/items").Header().Get(CacheStatusHeader))
+	assert.Equal(t, 2, calls)
+
+	// 使用较短的max-age作为缓存时间
+	mr.FastForward(6 * time.Second)
+	assert.Equal(t, "MISS", do(http.MethodGet, "/items").Header().Get(CacheStatusHeader))
+	assert.Equal(t, 3, calls)
+
+	// max-age=0 不缓存
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, "live", do(http.MethodGet, "/live").Body.String())
+	}
+	assert.Equal(t, 5, calls)
+}
+
+func TestResponseCacheSkipsErrorCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, _ := newTestRedisClient(t)
+	rc := NewResponseCache(client, nil)
+
+	calls := 0
+	r := gin.New()
+	r.GET("/orders", rc.Cache(time.Minute), func(c *gin.Context) {
+		calls++
+		xhttp.Error(c, xerror.SystemError)
+	})
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), xerror.SystemError.Message)
+		assert.Empty(t, w.Header().Get(CacheStatusHeader))
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestResponseCacheWait(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, mr := newTestRedisClient(t)
+	rc := NewResponseCache(client, nil)
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := gin.New()
+	r.GET("/orders", rc.Cache(time.Minute), func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+
+	// 第一个请求回源期间，第二个请求等待缓存写入而不是重复回源
+	responses := make([]*httptest.ResponseRecorder, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		responses[0] = httptest.NewRecorder()
+		r.ServeHTTP(responses[0], httptest.NewRequest(http.MethodGet, "/orders", nil))
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		responses[1] = httptest.NewRecorder()
+		r.ServeHTTP(responses[1], httptest.NewRequest(http.MethodGet, "/orders", nil))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, "MISS", responses[0].Header().Get(CacheStatusHeader))
+	assert.Equal(t, "HIT", responses[1].Header().Get(CacheStatusHeader))
+	assert.Equal(t, responses[0].Body.String(), responses[1].Body.String())
+	for _, key := range mr.Keys() {
+		assert.NotContains(t, key, responseCacheLockPrefix)
+	}
+}
+
+func TestResponseCacheLockExpired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	client, mr := newTestRedisClient(t)
+	rc := NewResponseCache(client, nil)
+
+	var lockKey string
+	r := gin.New()
+	r.GET("/orders", rc.Cache(time.Minute), func(c *gin.Context) {
+		lockKey = responseCacheLockPrefix + rc.cacheKey(c, false)
+		// 回源超过lockTTL，锁过期后被其他请求获取
+		mr.FastForward(rc.lockTTL)
+		_, err := client.TryLock(context.Background(), lockKey, time.Minute)
+		require.NoError(t, err)
+		c.JSON(http.StatusOK, gin.H{"id": 1})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	// 结束时不会删除其他请求持有的锁
+	assert.True(t, mr.Exists(lockKey))
+}
@@ -0,0 +1,137 @@
+package xmiddleware
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type securityHeadersOptions struct {
+	hstsMaxAge            time.Duration
+	hstsIncludeSubDomains bool
+	hstsPreload           bool
+	contentSecurityPolicy string
+	cspReportOnly         bool
+	contentTypeNosniff    bool
+	frameOptions          string
+	referrerPolicy        string
+	permissionsPolicy     string
+}
+
+// SecurityHeadersOption 安全响应头配置项，值为空字符串时不设置对应的响应头
+type SecurityHeadersOption func(*securityHeadersOptions)
+
+// WithHSTS 设置 Strict-Transport-Security，maxAge为0时不设置，默认1年并包含子域名
+func WithHSTS(maxAge time.Duration, includeSubDomains, preload bool) SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.hstsMaxAge = maxAge
+		o.hstsIncludeSubDomains = includeSubDomains
+		o.hstsPreload = preload
+	}
+}
+
+// WithContentSecurityPolicy 设置 Content-Security-Policy，默认 "default-src 'self'; frame-ancestors 'none'"
+func WithContentSecurityPolicy(policy string) SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.contentSecurityPolicy = policy
+	}
+}
+
+// WithCSPReportOnly 使用 Content-Security-Policy-Report-Only，只上报不拦截，用于新策略试运行
+func WithCSPReportOnly() SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.cspReportOnly = true
+	}
+}
+
+// WithoutContentTypeNosniff 不设置 X-Content-Type-Options: nosniff
+func WithoutContentTypeNosniff() SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.contentTypeNosniff = false
+	}
+}
+
+// WithFrameOptions 设置 X-Frame-Options，默认 DENY
+func WithFrameOptions(value string) SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.frameOptions = value
+	}
+}
+
+// WithReferrerPolicy 设置 Referrer-Policy，默认 strict-origin-when-cross-origin
+func WithReferrerPolicy(policy string) SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.referrerPolicy = policy
+	}
+}
+
+// WithPermissionsPolicy 设置 Permissions-Policy，默认禁用摄像头、麦克风和定位
+func WithPermissionsPolicy(policy string) SecurityHeadersOption {
+	return func(o *securityHeadersOptions) {
+		o.permissionsPolicy = policy
+	}
+}
+
+// SecurityHeaders 设置常用的安全响应头。HSTS只在HTTPS请求（包括代理转发的）中设置
+func SecurityHeaders(opts ...SecurityHeadersOption) gin.HandlerFunc {
+	o := &securityHeadersOptions{
+		hstsMaxAge:            365 * 24 * time.Hour,
+		hstsIncludeSubDomains: true,
+		contentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'",
+		contentTypeNosniff:    true,
+		frameOptions:          "DENY",
+		referrerPolicy:        "strict-origin-when-cross-origin",
+		permissionsPolicy:     "camera=(), microphone=(), geolocation=()",
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var hsts string
+	if o.hstsMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(o.hstsMaxAge/time.Second), 10)
+		if o.hstsIncludeSubDomains {
+			hsts += "; includeSubDomains"
+		}
+		if o.hstsPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader := "Content-Security-Policy"
+	if o.cspReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		if hsts != "" && isHTTPS(c) {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		if o.contentSecurityPolicy != "" {
+			h.Set(cspHeader, o.contentSecurityPolicy)
+		}
+		if o.contentTypeNosniff {
+			h.Set("X-Content-Type-Options", "nosniff")
+		}
+		if o.frameOptions != "" {
+			h.Set("X-Frame-Options", o.frameOptions)
+		}
+		if o.referrerPolicy != "" {
+			h.Set("Referrer-Policy", o.referrerPolicy)
+		}
+		if o.permissionsPolicy != "" {
+			h.Set("Permissions-Policy", o.permissionsPolicy)
+		}
+		c.Next()
+	}
+}
+
+// isHTTPS 判断请求是否通过HTTPS访问
+func isHTTPS(c *gin.Context) bool {
+	if c.Request.TLS != nil {
+		return true
+	}
+	return strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
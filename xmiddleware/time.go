@@ -6,20 +6,11 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type copyWriter struct {
-	gin.ResponseWriter
-}
-
-func (cw copyWriter) Write(b []byte) (int, error) {
+var (
 	// 匹配零值时间格式 (0001-01-01 00:00:00 或 0001-01-01T00:00:00Z 等)
-	zeroTimePattern := regexp.MustCompile(`["|']?\d{4}-01-01[T ]00:00:00\.?\d*Z?["|']?`)
-	s := string(b)
-
-	// 将零值时间替换为空字符串
-	s = zeroTimePattern.ReplaceAllString(s, `""`)
-
+	zeroTimePattern = regexp.MustCompile(`["|']?\d{4}-01-01[T ]00:00:00\.?\d*Z?["|']?`)
 	// 匹配 ISO8601/RFC3339 格式的时间字符串
-	timePatterns := []*regexp.Regexp{
+	timePatterns = []*regexp.Regexp{
 		// 匹配带毫秒的格式
 		regexp.MustCompile(`(\d{4}-\d{2}-\d{2})T(\d{2}:\d{2}:\d{2})\.?\d*([+-]\d{2}:?\d{2})?`),
 		// 匹配不带毫秒的格式
@@ -27,6 +18,17 @@ func (cw copyWriter) Write(b []byte) (int, error) {
 		// 匹配Z结尾的UTC时间格式
 		regexp.MustCompile(`(\d{4}-\d{2}-\d{2})T(\d{2}:\d{2}:\d{2})\.?\d*Z`),
 	}
+)
+
+type copyWriter struct {
+	gin.ResponseWriter
+}
+
+func (cw copyWriter) Write(b []byte) (int, error) {
+	s := string(b)
+
+	// 将零值时间替换为空字符串
+	s = zeroTimePattern.ReplaceAllString(s, `""`)
 
 	// 依次应用所有正则表达式格式化非零时间
 	for _, pattern := range timePatterns {
@@ -36,6 +38,11 @@ func (cw copyWriter) Write(b []byte) (int, error) {
 	return cw.ResponseWriter.WriteString(s)
 }
 
+// TimeFormat 通过正则改写响应体中的时间字符串，零值时间改为空字符串，其他时间改为 2006-01-02 15:04:05。
+// 会误改用户数据中形如时间的字符串。
+//
+// Deprecated: 响应结构体中的时间字段请使用 xhttp.Time，并通过 xhttp.SetTimeFormat
+// 配置布局、时区和零值策略
 func TimeFormat(ctx *gin.Context) {
 	cw := &copyWriter{ResponseWriter: ctx.Writer}
 	ctx.Writer = cw
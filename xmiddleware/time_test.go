@@ -0,0 +1,23 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TimeFormat)
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, `{"created":"2024-05-01T08:30:00+08:00","deleted":"0001-01-01T00:00:00Z"}`)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, `{"created":"2024-05-01 08:30:00","deleted":""}`, w.Body.String())
+}
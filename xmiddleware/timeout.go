@@ -0,0 +1,219 @@
+package xmiddleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+	"github.com/RichXan/xcommon/xhttp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimeoutKey 请求超时后在上下文中设置的标记，供日志中间件记录
+const TimeoutKey = "timeout"
+
+// timeoutWriter 缓冲处理函数的响应，超时后丢弃处理函数的写入
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	written  bool
+	timedOut bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		header:         make(http.Header),
+		status:         http.StatusOK,
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.written {
+		return
+	}
+	w.status = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.written = true
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.written
+}
+
+// Flush 响应在处理完成前被缓冲，不支持提前刷新
+func (w *timeoutWriter) Flush() {}
+
+// Timeout 请求超时中间件，为 c.Request.Context() 设置截止时间，
+// 超时后返回 xerror.Timeout，处理函数之后的写入会被丢弃。
+// 处理函数的响应在完成前会被缓冲，不适用于流式响应
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		runWithTimeout(c, timeout)
+	}
+}
+
+// TimeoutByRoute 按路由设置超时时间，routes的key为 "METHOD 路由模板"，如 "GET /users/:id"，
+// 未配置的路由使用defaultTimeout，超时时间小于等于0表示不限制
+func TimeoutByRoute(defaultTimeout time.Duration, routes map[string]time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := defaultTimeout
+		if t, ok := routes[c.Request.Method+" "+c.FullPath()]; ok {
+			timeout = t
+		}
+		runWithTimeout(c, timeout)
+	}
+}
+
+func runWithTimeout(c *gin.Context, timeout time.Duration) {
+	if timeout <= 0 {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+
+	original := c.Writer
+	tw := newTimeoutWriter(original)
+	c.Writer = tw
+
+	deadline, _ := ctx.Deadline()
+	done := make(chan struct{})
+	var (
+		panicked   *handlerPanic
+		finishedAt time.Time
+	)
+	go func() {
+		defer close(done)
+		defer func() {
+			finishedAt = time.Now()
+			if p := recover(); p != nil {
+				// 在处理函数的goroutine中记录堆栈，重新抛出后仍能定位原始位置
+				panicked = &handlerPanic{value: p, stack: debug.Stack()}
+			}
+		}()
+		c.Next()
+	}()
+
+	// 按完成时间判断处理函数是否在截止时间前完成，响应ctx取消后才返回的处理函数视为超时
+	finished := false
+	select {
+	case <-done:
+		finished = finishedAt.Before(deadline)
+	case <-ctx.Done():
+		select {
+		case <-done:
+			finished = finishedAt.Before(deadline)
+		default:
+		}
+	}
+	// 客户端主动断开时不返回超时响应
+	if !finished && ctx.Err() == context.DeadlineExceeded {
+		tw.mu.Lock()
+		tw.timedOut = true
+		tw.mu.Unlock()
+		c.Set(TimeoutKey, true)
+		writeTimeoutResponse(c, original)
+	}
+	// 等待处理函数退出后再归还gin.Context，避免与后续请求竞争
+	<-done
+
+	c.Writer = original
+	if panicked != nil {
+		// http.ErrAbortHandler 按标准库约定原样抛出
+		if panicked.value == http.ErrAbortHandler {
+			panic(panicked.value)
+		}
+		panic(panicked)
+	}
+	if tw.timedOut {
+		c.Abort()
+		return
+	}
+
+	dst := original.Header()
+	for k, v := range tw.header {
+		dst[k] = v
+	}
+	original.WriteHeader(tw.status)
+	if tw.written {
+		original.WriteHeaderNow()
+	}
+	if tw.body.Len() > 0 {
+		_, _ = original.Write(tw.body.Bytes())
+	}
+}
+
+// handlerPanic 处理函数goroutine中的panic及其原始堆栈，Recovery 会还原为原始的panic值
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\nhandler goroutine stack:\n%s", p.value, p.stack)
+}
+
+// writeTimeoutResponse 直接向原始writer写入超时响应
+func writeTimeoutResponse(c *gin.Context, w gin.ResponseWriter) {
+	c.Set(xhttp.ErrorCodeKey, xerror.Timeout.Code)
+	resp := xhttp.NewResponse(xerror.Timeout).WithTraceID(c.GetString("trace_id"))
+	body, _ := json.Marshal(resp)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = w.Write(body)
+	w.Flush()
+}
@@ -0,0 +1,76 @@
+package xmiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RichXan/xcommon/xerror"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TimeoutByRoute(50*time.Millisecond, map[string]time.Duration{
+		"GET /fast-limit": 10 * time.Millisecond,
+	}))
+	r.GET("/ok", func(c *gin.Context) {
+		c.Header("X-Handler", "ok")
+		c.String(http.StatusCreated, "done")
+	})
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(time.Second):
+		}
+		c.String(http.StatusOK, "late")
+	}
+	r.GET("/slow", slow)
+	r.GET("/fast-limit", func(c *gin.Context) {
+		time.Sleep(30 * time.Millisecond)
+		c.String(http.StatusOK, "late")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "ok", w.Header().Get("X-Handler"))
+	assert.Equal(t, "done", w.Body.String())
+
+	for _, path := range []string{"/slow", "/fast-limit"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), xerror.Timeout.Message)
+		assert.NotContains(t, w.Body.String(), "late")
+	}
+}
+
+func TestTimeoutPanicStack(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	notified := make(chan *PanicEvent, 1)
+	r := gin.New()
+	r.Use(Recovery(newTestLogger(), WithPanicNotifier(func(event *PanicEvent) {
+		notified <- event
+	})), Timeout(time.Second))
+	r.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// 保留处理函数中的原始panic值和堆栈
+	select {
+	case event := <-notified:
+		assert.Equal(t, "boom", event.Error)
+		assert.Contains(t, event.Stack, "TestTimeoutPanicStack.func")
+	case <-time.After(time.Second):
+		t.Fatal("notifier not called")
+	}
+}
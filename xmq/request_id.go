@@ -0,0 +1,57 @@
+package xmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/RichXan/xcommon/xutil"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+)
+
+// InjectRequestID 将context中的请求ID写入消息属性
+func InjectRequestID(ctx context.Context, msg *pulsar.ProducerMessage) {
+	requestID := xutil.RequestIDFromContext(ctx)
+	if requestID == "" {
+		return
+	}
+	if msg.Properties == nil {
+		msg.Properties = make(map[string]string)
+	}
+	if _, ok := msg.Properties[xutil.RequestIDProperty]; !ok {
+		msg.Properties[xutil.RequestIDProperty] = requestID
+	}
+}
+
+// ContextWithMessage 从消息属性中取出请求ID保存到context，用于消费时关联日志
+func ContextWithMessage(ctx context.Context, msg pulsar.Message) context.Context {
+	if requestID := msg.Properties()[xutil.RequestIDProperty]; requestID != "" {
+		return xutil.WithRequestID(ctx, requestID)
+	}
+	return ctx
+}
+
+// SendContext 发送消息，并传递context中的请求ID
+func (p *Pulsar) SendContext(ctx context.Context, msg pulsar.ProducerMessage) error {
+	if p.Producer == nil {
+		return errors.New("producer is nil")
+	}
+	InjectRequestID(ctx, &msg)
+	_, err := p.Producer.Send(ctx, &msg)
+	return err
+}
+
+// SendJsonContext 发送json数据，并传递context中的请求ID
+func (p *Pulsar) SendJsonContext(ctx context.Context, data interface{}) (pulsar.MessageID, error) {
+	if p.Producer == nil {
+		return nil, errors.New("producer is nil")
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	msg := &pulsar.ProducerMessage{Payload: jsonData}
+	InjectRequestID(ctx, msg)
+	return p.Producer.Send(ctx, msg)
+}
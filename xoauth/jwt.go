@@ -45,6 +45,9 @@ type Info struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
 	Data     any    `json:"data"`
+	// 角色和权限，由RBAC中间件校验
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
 }
 
 type Config struct {
@@ -0,0 +1,148 @@
+package xutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// RequestIDHeader 传递请求ID的HTTP请求头
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDProperty 传递请求ID的消息属性名
+	RequestIDProperty = "request_id"
+	// RequestIDMaxLength 请求ID的最大长度
+	RequestIDMaxLength = 64
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID 将请求ID保存到context中
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext 从context中获取请求ID，不存在时返回空字符串
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// ValidRequestID 校验请求ID：不超过64个字符，只包含字母、数字和 - _ . :
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > RequestIDMaxLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		ch := requestID[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '_', ch == '.', ch == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDGenerator 请求ID生成器
+type RequestIDGenerator func() string
+
+// UUIDv4 生成随机UUID
+func UUIDv4() string {
+	return uuid.NewString()
+}
+
+// UUIDv7 生成按时间排序的UUID，失败时退化为UUIDv4
+func UUIDv7() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// crockford ULID使用的Base32字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID 生成26个字符的ULID：48位毫秒时间戳和80位随机数
+func ULID() string {
+	var data [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	data[0] = byte(ms >> 40)
+	data[1] = byte(ms >> 32)
+	data[2] = byte(ms >> 24)
+	data[3] = byte(ms >> 16)
+	data[4] = byte(ms >> 8)
+	data[5] = byte(ms)
+	if _, err := rand.Read(data[6:]); err != nil {
+		panic(err)
+	}
+
+	// 128位按5位一组编码，最高位补两个0
+	hi := binary.BigEndian.Uint64(data[:8])
+	lo := binary.BigEndian.Uint64(data[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// snowflake 各部分的位数
+const (
+	snowflakeNodeBits     = 10
+	snowflakeSequenceBits = 12
+	snowflakeMaxNode      = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSequence  = 1<<snowflakeSequenceBits - 1
+)
+
+// snowflakeEpoch 雪花ID的起始时间 2024-01-01 00:00:00 UTC
+var snowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+// NewSnowflakeGenerator 创建雪花ID生成器，node为0-1023的节点编号，多实例部署时不能重复
+func NewSnowflakeGenerator(node int64) (RequestIDGenerator, error) {
+	if node < 0 || node > snowflakeMaxNode {
+		return nil, errors.New("snowflake node must be between 0 and " + strconv.Itoa(snowflakeMaxNode))
+	}
+	var (
+		mu       sync.Mutex
+		last     int64
+		sequence int64
+	)
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now().UnixMilli() - snowflakeEpoch
+		// 时钟回拨时沿用上次的时间戳
+		if now < last {
+			now = last
+		}
+		if now == last {
+			sequence = (sequence + 1) & snowflakeMaxSequence
+			if sequence == 0 {
+				// 当前毫秒的序列号用完，等待下一毫秒
+				for now <= last {
+					time.Sleep(100 * time.Microsecond)
+					now = time.Now().UnixMilli() - snowflakeEpoch
+				}
+			}
+		} else {
+			sequence = 0
+		}
+		last = now
+		id := now<<(snowflakeNodeBits+snowflakeSequenceBits) | node<<snowflakeSequenceBits | sequence
+		return strconv.FormatInt(id, 10)
+	}, nil
+}